@@ -1,22 +1,29 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strconv"
-	"sync"
+	"time"
 	"trading/pages"
+	"trading/repository"
 )
 
 // Handler for a routing
 type Handler struct {
-	DB          *sql.DB
-	AccountPage *pages.AccountPage
+	// Account is the name of the account, rows of the tables are filtered by it
+	Account string
+	Items   repository.ItemRepository
+	Orders  repository.OrderRepository
+	Broker  pages.Broker
+	Session *pages.Session
+	// Positions is the snapshot cache of the positions, they are read live if it's nil
+	Positions *PositionCache
 }
 
 // Status of the query
@@ -35,75 +42,6 @@ type Response struct {
 	ID      int64 `json:"id"`
 }
 
-func (h *Handler) findID(id int) (string, error) {
-	rows, err := h.DB.Query("SELECT item_key FROM items WHERE item_id = ?;", id)
-	if err != nil {
-		return "", err
-	}
-	var guid string
-	for rows.Next() {
-		err = rows.Scan(&guid)
-		if err != nil {
-			return "", err
-		}
-	}
-	defer rows.Close()
-	return guid, nil
-}
-
-func (h *Handler) findItem(id int) (*pages.DbItem, error) {
-	rows, err := h.DB.Query("SELECT * FROM items WHERE item_id = ?;", id)
-	if err != nil {
-		return nil, err
-	}
-	item := pages.DbItem{}
-	for rows.Next() {
-		err = rows.Scan(&item.ID, &item.Instrument, &item.GUID, &item.Dir, &item.Qty, &item.Price)
-		if err != nil {
-			return nil, err
-		}
-	}
-	defer rows.Close()
-	return &item, nil
-}
-
-func (h *Handler) edit(item *pages.DbItem) error {
-	cmd := "UPDATE items SET qty = ? WHERE item_id = ?"
-	itemUpdate, err := h.DB.Prepare(cmd)
-	if err != nil {
-		return err
-	}
-
-	args := make([]interface{}, 0)
-	args = append(args, item.Qty)
-	args = append(args, item.ID)
-	result, err := itemUpdate.Exec(args...)
-
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	msg := fmt.Sprintf("Edit. RowsAffected: %d, id: %d", affected, item.ID)
-	log.Debug(msg)
-
-	return nil
-}
-
-func (h *Handler) deleteID(id int) error {
-	deleteItem, err := h.DB.Prepare("DELETE FROM items WHERE item_id = ?")
-	if err != nil {
-		return err
-	}
-	result, err := deleteItem.Exec(id)
-	affected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	msg := fmt.Sprintf("Delete. RowsAffected: %d, id: %d", affected, id)
-	log.Debug(msg)
-	return nil
-}
-
 // GetPosition godoc
 // @Summary Get details of the position
 // @Description Get details of the position
@@ -112,21 +50,21 @@ func (h *Handler) deleteID(id int) error {
 // @Produce  json
 // @Param id path int true "Position ID"
 // @Success 200 {object} pages.Position
-// @Router /positions/{id} [get]
+// @Router /accounts/{name}/positions/{id} [get]
 func (h *Handler) GetPosition(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id := params["id"]
 	result, _ := strconv.Atoi(id)
-	guid, err := h.findID(result)
+	guid, err := h.Items.FindGUID(result)
 
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	position, err := h.AccountPage.GetPosition(guid)
+	position, err := h.Broker.GetPosition(guid)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithBrokerError(w, err)
 		return
 	}
 	position.ID = result
@@ -135,43 +73,87 @@ func (h *Handler) GetPosition(w http.ResponseWriter, r *http.Request) {
 
 // GetPositions godoc
 // @Summary Get details of all positions
-// @Description Get details of all positions
+// @Description Get details of all positions from the snapshot cache, fresh=true reads them from the platform
+// @Tags positions
+// @Produce json
+// @Param fresh query bool false "Read the positions from the platform"
+// @Success 200 {object} PositionsResponse
+// @Router /accounts/{name}/positions [get]
 func (h *Handler) GetPositions(w http.ResponseWriter, r *http.Request) {
-	rows, err := h.DB.Query("SELECT item_id, item_key FROM items;")
+	guids, err := h.Items.Opened()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	guids := make(map[int]string, 0)
-	for rows.Next() {
-		var guid string
-		var id int
-		err = rows.Scan(&id, &guid)
+
+	response := &PositionsResponse{AsOf: time.Now().UTC()}
+	found := make(map[string]*pages.Position, len(guids))
+	var snapshot *Snapshot
+	if h.Positions != nil && r.URL.Query().Get("fresh") != "true" {
+		snapshot, err = h.Positions.Get()
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+			response.Error = err.Error()
+		}
+	}
+	if snapshot != nil {
+		response.AsOf = snapshot.AsOf
+		response.Stale = h.Positions.Stale(snapshot)
+		for _, guid := range guids {
+			if position, ok := snapshot.Positions[guid]; ok {
+				found[guid] = position
+			}
+		}
+	}
+
+	// the positions opened or changed after the snapshot are read from the platform
+	ids := make([]string, 0, len(guids))
+	for _, guid := range guids {
+		if _, ok := found[guid]; !ok {
+			ids = append(ids, guid)
+		}
+	}
+	if len(ids) > 0 {
+		live, err := h.Broker.GetPositions(ids)
+		if err != nil && snapshot == nil {
+			respondWithBrokerError(w, err)
 			return
 		}
-		guids[id] = guid
+		// the snapshot is returned as it is if the platform isn't available
+		if err != nil {
+			response.Error = err.Error()
+		}
+		for guid, position := range live {
+			found[guid] = position
+		}
 	}
-	defer rows.Close()
 
-	positions := make([]*pages.Position, 0)
-	wg := &sync.WaitGroup{}
+	response.Age = time.Since(response.AsOf).Seconds()
+	response.Positions = make([]*pages.Position, 0, len(guids))
 	for id, guid := range guids {
-		wg.Add(1)
-		go func(id int, guid string, wg *sync.WaitGroup) {
-			defer wg.Done()
-			position, err := h.AccountPage.GetPosition(guid)
-			if err != nil {
-				respondWithError(w, http.StatusInternalServerError, err.Error())
-				return
-			}
-			position.ID = id
-			positions = append(positions, position)
-		}(id, guid, wg)
+		position, ok := found[guid]
+		if !ok {
+			response.Missing = append(response.Missing, id)
+			continue
+		}
+		// the positions of the snapshot are shared between the requests
+		copied := *position
+		copied.ID = id
+		response.Positions = append(response.Positions, &copied)
+	}
+	sort.Ints(response.Missing)
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// logger returns the log entry of the account, the mode is added to it by pages.ModeHook
+func (h *Handler) logger() *log.Entry {
+	return log.WithField("account", h.Account)
+}
+
+// forget drops the changed position from the cache
+func (h *Handler) forget(guid string) {
+	if h.Positions != nil {
+		h.Positions.Forget(guid)
 	}
-	wg.Wait()
-	respondWithJSON(w, http.StatusOK, positions)
 }
 
 // DeletePosition deletes an opened position
@@ -179,7 +161,7 @@ func (h *Handler) DeletePosition(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	data := params["id"]
 	id, _ := strconv.Atoi(data)
-	guid, err := h.findID(id)
+	guid, err := h.Items.FindGUID(id)
 
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
@@ -191,14 +173,16 @@ func (h *Handler) DeletePosition(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.AccountPage.DeletePosition(guid)
+	position, err := h.Broker.DeletePosition(guid)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithBrokerError(w, err)
 		return
 	}
-	err = h.deleteID(id)
+	h.forget(guid)
+	err = h.Items.Close(&pages.DbItem{ID: id, ClosePrice: position.CurrentPrice, Result: position.Result})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 	response := &Response{ID: int64(id), Message: "Item is deleted", Status: Success}
 	respondWithJSON(w, http.StatusOK, response)
@@ -209,81 +193,306 @@ func (h *Handler) EditPosition(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	data := params["id"]
 	id, _ := strconv.Atoi(data)
-	item, err := h.findItem(id)
 
-	bytes, err := ioutil.ReadAll(r.Body)
 	defer r.Body.Close()
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+	req := &EditRequest{}
+	if err := decodeStrict(r.Body, req); err != nil {
+		respondWithValidation(w, err)
+		return
+	}
+	if errs := req.validate(); len(errs) != 0 {
+		respondWithValidation(w, errs)
 		return
 	}
 
-	jsonMap := make(map[string]interface{})
-	err = json.Unmarshal(bytes, &jsonMap)
-
-	position, err := h.AccountPage.EditPosition(item, jsonMap)
+	item, err := h.Items.Find(id)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if item.GUID == "" {
+		msg := fmt.Sprintf(pages.GUIDNotFound, id)
+		respondWithError(w, http.StatusNotFound, msg)
+		return
+	}
+
+	position, err := h.Broker.EditPosition(item, req.Quantity)
+	if err != nil {
+		respondWithBrokerError(w, err)
+		return
+	}
+	h.forget(item.GUID)
 
-	err = h.edit(position)
+	err = h.Items.UpdateQty(position)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 	response := &Response{ID: int64(position.ID), Message: "Item is edited", Status: Success}
 	respondWithJSON(w, http.StatusOK, response)
 }
 
-// Add godoc
-// @Summary Create a new position
-// @Description Create a new position with the input data
+// ClosePosition godoc
+// @Summary Close the position
+// @Description Close an absolute quantity, a percentage or down to the target quantity
 // @Tags positions
 // @Accept json
 // @Produce json
+// @Param id path int true "Position ID"
+// @Param close body CloseRequest true "Quantity to close"
 // @Success 200 {object} Response
-// @Router /positions [post]
-func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
-	bytes, err := ioutil.ReadAll(r.Body)
+// @Failure 400 {object} ValidationErrors
+// @Router /accounts/{name}/positions/{id}/close [post]
+func (h *Handler) ClosePosition(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	data := params["id"]
+	id, _ := strconv.Atoi(data)
+
 	defer r.Body.Close()
+	req := &CloseRequest{}
+	if err := decodeStrict(r.Body, req); err != nil {
+		respondWithValidation(w, err)
+		return
+	}
+	if errs := req.validate(); len(errs) != 0 {
+		respondWithValidation(w, errs)
+		return
+	}
+
+	item, err := h.Items.Find(id)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if item.GUID == "" {
+		msg := fmt.Sprintf(pages.GUIDNotFound, id)
+		respondWithError(w, http.StatusNotFound, msg)
+		return
+	}
+
+	position, err := h.Broker.ClosePosition(item, req.payload())
+	if err != nil {
+		respondWithBrokerError(w, err)
 		return
 	}
+	h.forget(item.GUID)
+
+	message := "Item is partially closed"
+	if position.Qty == 0 {
+		message = "Item is closed"
+		err = h.Items.Close(position)
+	} else {
+		err = h.Items.UpdateQty(position)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response := &Response{ID: int64(id), Message: message, Status: Success}
+	respondWithJSON(w, http.StatusOK, response)
+}
 
-	jsonMap := make(map[string]interface{})
-	err = json.Unmarshal(bytes, &jsonMap)
+// EditLimits godoc
+// @Summary Edit limits of the position
+// @Description Set, move or remove take-profit, stop-loss and trailing stop
+// @Tags positions
+// @Accept json
+// @Produce json
+// @Param id path int true "Position ID"
+// @Param limits body EditLimitsRequest true "New limits"
+// @Success 200 {object} pages.Position
+// @Failure 400 {object} ValidationErrors
+// @Router /accounts/{name}/positions/{id}/limits [put]
+func (h *Handler) EditLimits(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	data := params["id"]
+	id, _ := strconv.Atoi(data)
 
-	item, err := h.AccountPage.Add(jsonMap)
+	defer r.Body.Close()
+	req := &EditLimitsRequest{}
+	if err := decodeStrict(r.Body, req); err != nil {
+		respondWithValidation(w, err)
+		return
+	}
+
+	item, err := h.Items.Find(id)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	result, err := h.DB.Exec(
-		"INSERT INTO items (`instrument`, `item_key`, `direction`, `qty`, `price`) VALUES (?, ?, ?, ?, ?)",
-		item.Instrument, item.Key, item.Direction, item.Qty, item.Price,
-	)
+	if item.GUID == "" {
+		msg := fmt.Sprintf(pages.GUIDNotFound, id)
+		respondWithError(w, http.StatusNotFound, msg)
+		return
+	}
+	if errs := req.validate(item.Dir); len(errs) != 0 {
+		respondWithValidation(w, errs)
+		return
+	}
+
+	position, err := h.Broker.EditLimits(item.GUID, req.payload())
+	if err != nil {
+		respondWithBrokerError(w, err)
+		return
+	}
+	h.forget(item.GUID)
+	position.ID = id
+	respondWithJSON(w, http.StatusOK, position)
+}
+
+// Add godoc
+// @Summary Create a new position
+// @Description Create a new position with the input data
+// @Tags positions
+// @Accept json
+// @Produce json
+// @Param position body AddRequest true "New position"
+// @Success 200 {object} Response
+// @Failure 400 {object} ValidationErrors
+// @Router /accounts/{name}/positions [post]
+func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
+	item, ok := decodeAdd(w, r, false)
+	if !ok {
+		return
+	}
+
+	// the intent is recorded as executing first, so a position opened
+	// before a crash is found by ResolveIntents
+	lastID, err := h.Items.Intend(item)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	affected, err := result.RowsAffected()
+	item, err = h.Broker.Add(item)
 	if err != nil {
-		respondWithError(w, http.StatusNotModified, err.Error())
+		// the position is opened, but it's not known which one it is
+		intent := repository.IntentFailed
+		if pages.Unconfirmed(err) {
+			intent = repository.IntentExecuting
+		}
+		if err := h.Items.SetIntent(lastID, intent, err.Error()); err != nil {
+			h.logger().Errorf("Intent %d is left executing: %s", lastID, err)
+		}
+		respondWithBrokerError(w, err)
 		return
 	}
-	lastID, err := result.LastInsertId()
+	err = h.Items.Confirm(lastID, item)
 	if err != nil {
-		respondWithError(w, http.StatusNotModified, err.Error())
+		h.logger().Errorf("Intent %d is left executing, the position %s is opened: %s", lastID, item.Key, err)
+		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	log.Debug(fmt.Sprintf("Insert: rowsAffected: %d, lastInsertedId: %d", affected, lastID))
 
 	response := &Response{ID: lastID, Message: "Item is added", Status: Success}
 	respondWithJSON(w, http.StatusOK, response)
 }
 
+// GetStatus godoc
+// @Summary Get the login state
+// @Description Get the login state of the trading account
+// @Tags status
+// @Produce  json
+// @Success 200 {object} pages.SessionStatus
+// @Router /accounts/{name}/status [get]
+func (h *Handler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status := h.Session.Status()
+	code := http.StatusOK
+	if !status.LoggedIn {
+		code = http.StatusServiceUnavailable
+	}
+	respondWithJSON(w, code, status)
+}
+
+// ModePayload is for the mode switching
+type ModePayload struct {
+	Mode string `json:"mode"`
+}
+
+// SetMode godoc
+// @Summary Switch the account mode
+// @Description Log in again in demo or real mode
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} pages.SessionStatus
+// @Router /accounts/{name}/admin/mode [put]
+func (h *Handler) SetMode(w http.ResponseWriter, r *http.Request) {
+	bytes, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	payload := &ModePayload{}
+	err = json.Unmarshal(bytes, payload)
+	if err != nil || !pages.ValidMode(payload.Mode) {
+		msg := fmt.Sprintf("Mode must be %s or %s", pages.DEMO, pages.REAL)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	err = h.Session.SwitchMode(payload.Mode)
+	// the header is set before the switch, so it's updated here
+	w.Header().Set(ModeHeader, h.Session.CurrentMode())
+	if err != nil {
+		respondWithBrokerError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, h.Session.Status())
+}
+
+// ModeHeader is set on every response
+const ModeHeader = "X-Trading-Mode"
+
+// WithMode is a middleware which adds the current mode to the response
+func (h *Handler) WithMode(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ModeHeader, h.Session.CurrentMode())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// brokerStatuses maps codes of the broker errors to http statuses
+var brokerStatuses = map[string]int{
+	pages.CodeInsufficientFunds:  http.StatusPaymentRequired,
+	pages.CodeMarketClosed:       http.StatusConflict,
+	pages.CodeMaxQuantity:        http.StatusUnprocessableEntity,
+	pages.CodeMinQuantity:        http.StatusUnprocessableEntity,
+	pages.CodeInvalidQuantity:    http.StatusUnprocessableEntity,
+	pages.CodeInstrumentNotFound: http.StatusNotFound,
+	pages.CodePositionNotFound:   http.StatusNotFound,
+	pages.CodeOrderNotFound:      http.StatusNotFound,
+	pages.CodeSessionExpired:     http.StatusServiceUnavailable,
+	pages.CodeAmbiguousKey:       http.StatusConflict,
+	pages.CodeKeyNotFound:        http.StatusGatewayTimeout,
+	pages.CodeRejected:           http.StatusBadGateway,
+}
+
+// brokerStatus returns http status and code of the failed broker call
+func brokerStatus(err error) (int, string) {
+	switch e := err.(type) {
+	case *pages.LoginError:
+		return http.StatusServiceUnavailable, e.Code
+	case *pages.BrokerError:
+		if status, ok := brokerStatuses[e.Code]; ok {
+			return status, e.Code
+		}
+		return http.StatusInternalServerError, e.Code
+	}
+	return http.StatusInternalServerError, ""
+}
+
+// respondWithBrokerError responds with the status and the code of the failed broker call
+func respondWithBrokerError(w http.ResponseWriter, err error) {
+	status, code := brokerStatus(err)
+	if code == "" {
+		respondWithError(w, status, err.Error())
+		return
+	}
+	respondWithJSON(w, status, map[string]string{"error": err.Error(), "code": code})
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
 
@@ -296,18 +505,138 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
 
+// AddOrder godoc
+// @Summary Create a new pending order
+// @Description Create a new limit or stop order with the input data
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param order body AddRequest true "New order"
+// @Success 200 {object} Response
+// @Failure 400 {object} ValidationErrors
+// @Router /accounts/{name}/orders [post]
+func (h *Handler) AddOrder(w http.ResponseWriter, r *http.Request) {
+	item, ok := decodeAdd(w, r, true)
+	if !ok {
+		return
+	}
+
+	item, err := h.Broker.Add(item)
+	if err != nil {
+		respondWithBrokerError(w, err)
+		return
+	}
+	lastID, err := h.Orders.Insert(item)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := &Response{ID: lastID, Message: "Order is added", Status: Success}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
 // GetOrder godoc
 // @Summary Get details of the order
-// @Description Get details of the order
-/*func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
+// @Description Get details of the pending order
+// @Tags orders
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Order ID"
+// @Success 200 {object} pages.Order
+// @Router /accounts/{name}/orders/{id} [get]
+func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	data := params["id"]
+	id, _ := strconv.Atoi(data)
+	guid, err := h.Orders.FindGUID(id)
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if guid == "" {
+		msg := fmt.Sprintf(pages.GUIDNotFound, id)
+		respondWithError(w, http.StatusNotFound, msg)
+		return
+	}
+
+	order, err := h.Broker.GetOrder(guid)
+	if err != nil {
+		respondWithBrokerError(w, err)
+		return
+	}
+	order.ID = id
+	respondWithJSON(w, http.StatusOK, order)
 }
 
 // GetOrders godoc
 // @Summary Get details of all orders
-// @Description Get details of all orders
+// @Description Get details of all pending orders
+// @Tags orders
+// @Produce  json
+// @Success 200 {array} pages.Order
+// @Router /accounts/{name}/orders [get]
 func (h *Handler) GetOrders(w http.ResponseWriter, r *http.Request) {
+	guids, err := h.Orders.Pending()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	scraped, err := h.Broker.GetOrders()
+	if err != nil {
+		respondWithBrokerError(w, err)
+		return
+	}
+
+	orders := make([]*pages.Order, 0)
+	for id, guid := range guids {
+		order, ok := scraped[guid]
+		if !ok {
+			h.logger().Debug(fmt.Sprintf("Order %d is not found in the orders table", id))
+			continue
+		}
+		order.ID = id
+		orders = append(orders, order)
+	}
+	respondWithJSON(w, http.StatusOK, orders)
 }
 
-// DeleteOrder deletes an opened order
+// DeleteOrder godoc
+// @Summary Cancel the order
+// @Description Cancel the pending order
+// @Tags orders
+// @Produce  json
+// @Param id path int true "Order ID"
+// @Success 200 {object} Response
+// @Router /accounts/{name}/orders/{id} [delete]
 func (h *Handler) DeleteOrder(w http.ResponseWriter, r *http.Request) {
-}*/
+	params := mux.Vars(r)
+	data := params["id"]
+	id, _ := strconv.Atoi(data)
+	guid, err := h.Orders.FindGUID(id)
+
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if guid == "" {
+		msg := fmt.Sprintf(pages.GUIDNotFound, id)
+		respondWithError(w, http.StatusNotFound, msg)
+		return
+	}
+
+	err = h.Broker.DeleteOrder(guid)
+	if err != nil {
+		respondWithBrokerError(w, err)
+		return
+	}
+	err = h.Orders.Delete(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	response := &Response{ID: int64(id), Message: "Order is cancelled", Status: Success}
+	respondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"trading/pages"
+	"trading/repository"
+)
+
+// addPosition opens a position through the handler and returns its id
+func addPosition(t *testing.T, h *Handler, qty int) int64 {
+	t.Helper()
+	body := fmt.Sprintf(`{"instrument": "AAPL", "direction": "buy", "qty": %d, "price": 100}`, qty)
+	rr := serve(h.Add, "POST", "/accounts/test/positions", body, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("add: status %d: %s", rr.Code, rr.Body)
+	}
+	response := &Response{}
+	if err := json.Unmarshal(rr.Body.Bytes(), response); err != nil {
+		t.Fatal(err)
+	}
+	return response.ID
+}
+
+func idVars(id int64) map[string]string {
+	return map[string]string{"id": strconv.FormatInt(id, 10)}
+}
+
+// errorCode returns the code of the error response
+func errorCode(t *testing.T, body []byte) string {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		t.Fatal(err)
+	}
+	code, _ := data["code"].(string)
+	return code
+}
+
+func TestAdd(t *testing.T) {
+	broker := pages.NewMemoryBroker()
+	h := newTestHandler(t, broker)
+	id := addPosition(t, h, 2)
+
+	item, err := h.Items.Find(int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.GUID != "1" || item.Qty != 2 {
+		t.Errorf("item is %+v", item)
+	}
+	if _, err := broker.GetPosition(item.GUID); err != nil {
+		t.Error(err)
+	}
+
+	rr := serve(h.Add, "POST", "/accounts/test/positions", `{"instrument": "AAPL", "direction": "up", "qty": 1}`, nil)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid request: status %d", rr.Code)
+	}
+}
+
+func TestGetPosition(t *testing.T) {
+	h := newTestHandler(t, pages.NewMemoryBroker())
+	id := addPosition(t, h, 2)
+
+	rr := serve(h.GetPosition, "GET", "/accounts/test/positions/1", "", idVars(id))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	position := &pages.Position{}
+	if err := json.Unmarshal(rr.Body.Bytes(), position); err != nil {
+		t.Fatal(err)
+	}
+	if int64(position.ID) != id || position.Instrument != "AAPL" || position.Quantity != 2 {
+		t.Errorf("position is %+v", position)
+	}
+}
+
+func TestEditPosition(t *testing.T) {
+	broker := pages.NewMemoryBroker()
+	h := newTestHandler(t, broker)
+	id := addPosition(t, h, 2)
+
+	body := `{"quantity": {"direction": "buy", "value": 1}}`
+	rr := serve(h.EditPosition, "PUT", "/accounts/test/positions/1", body, idVars(id))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	item, err := h.Items.Find(int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Qty != 3 {
+		t.Errorf("quantity is %d, want 3", item.Qty)
+	}
+
+	rr = serve(h.EditPosition, "PUT", "/accounts/test/positions/99", body, idVars(99))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("unknown id: status %d", rr.Code)
+	}
+}
+
+func TestEditLimits(t *testing.T) {
+	h := newTestHandler(t, pages.NewMemoryBroker())
+	id := addPosition(t, h, 1)
+
+	body := `{"take_profit": {"is_use": true, "price": 110}, "stop_loss": {"is_use": true, "price": 90}}`
+	rr := serve(h.EditLimits, "PUT", "/accounts/test/positions/1/limits", body, idVars(id))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	position := &pages.Position{}
+	if err := json.Unmarshal(rr.Body.Bytes(), position); err != nil {
+		t.Fatal(err)
+	}
+	if int64(position.ID) != id || position.TakeProfit != "110" || position.StopLoss != "90" {
+		t.Errorf("position is %+v", position)
+	}
+
+	// the take-profit of a buy position is above its stop-loss
+	body = `{"take_profit": {"is_use": true, "price": 90}, "stop_loss": {"is_use": true, "price": 110}}`
+	rr = serve(h.EditLimits, "PUT", "/accounts/test/positions/1/limits", body, idVars(id))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("crossed limits: status %d", rr.Code)
+	}
+}
+
+func TestClosePosition(t *testing.T) {
+	h := newTestHandler(t, pages.NewMemoryBroker())
+	id := addPosition(t, h, 4)
+
+	rr := serve(h.ClosePosition, "POST", "/accounts/test/positions/1/close", `{"quantity": 1}`, idVars(id))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	item, err := h.Items.Find(int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Qty != 3 {
+		t.Errorf("quantity is %d, want 3", item.Qty)
+	}
+
+	rr = serve(h.ClosePosition, "POST", "/accounts/test/positions/1/close", `{"percent": 100}`, idVars(id))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	item, err = h.Items.Find(int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.GUID != "" {
+		t.Error("closed position is still open")
+	}
+
+	rr = serve(h.ClosePosition, "POST", "/accounts/test/positions/1/close", `{"quantity": 1, "percent": 10}`, idVars(id))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("two quantities: status %d", rr.Code)
+	}
+}
+
+func TestClosePositionMissingOnPlatform(t *testing.T) {
+	broker := pages.NewMemoryBroker()
+	h := newTestHandler(t, broker)
+	id := addPosition(t, h, 1)
+	if _, err := broker.DeletePosition("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := serve(h.ClosePosition, "POST", "/accounts/test/positions/1/close", `{"quantity": 1}`, idVars(id))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if code := errorCode(t, rr.Body.Bytes()); code != pages.CodePositionNotFound {
+		t.Errorf("code %q, want %q", code, pages.CodePositionNotFound)
+	}
+}
+
+// failingBroker fails every new position with the error
+type failingBroker struct {
+	*pages.MemoryBroker
+	err error
+}
+
+func (b *failingBroker) Add(item *pages.Item) (*pages.Item, error) {
+	return nil, b.err
+}
+
+func TestAddBrokerErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+		intent string
+	}{
+		{pages.ErrSessionExpired, http.StatusServiceUnavailable, pages.CodeSessionExpired, repository.IntentFailed},
+		{&pages.BrokerError{Code: pages.CodeMarketClosed, Message: "closed"}, http.StatusConflict, pages.CodeMarketClosed, repository.IntentFailed},
+		{&pages.BrokerError{Code: pages.CodeKeyNotFound, Message: "no row"}, http.StatusGatewayTimeout, pages.CodeKeyNotFound, repository.IntentExecuting},
+	}
+	for _, tt := range tests {
+		h := newTestHandler(t, &failingBroker{MemoryBroker: pages.NewMemoryBroker(), err: tt.err})
+		rr := serve(h.Add, "POST", "/accounts/test/positions", `{"instrument": "AAPL", "direction": "buy", "qty": 1}`, nil)
+		if rr.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.code, rr.Code, tt.status)
+		}
+		if code := errorCode(t, rr.Body.Bytes()); code != tt.code {
+			t.Errorf("%s: code %q", tt.code, code)
+		}
+		// an unconfirmed position is left for ResolveIntents
+		intents, err := h.Items.Unresolved(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		executing := len(intents) == 1 && intents[0].Intent == repository.IntentExecuting
+		if executing != (tt.intent == repository.IntentExecuting) {
+			t.Errorf("%s: unresolved intents %+v, want %s", tt.code, intents, tt.intent)
+		}
+	}
+}
+
+func TestBrokerStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{&pages.BrokerError{Code: pages.CodeInsufficientFunds}, http.StatusPaymentRequired, pages.CodeInsufficientFunds},
+		{&pages.BrokerError{Code: pages.CodeMaxQuantity}, http.StatusUnprocessableEntity, pages.CodeMaxQuantity},
+		{&pages.BrokerError{Code: pages.CodeInstrumentNotFound}, http.StatusNotFound, pages.CodeInstrumentNotFound},
+		{&pages.BrokerError{Code: pages.CodeOrderNotFound}, http.StatusNotFound, pages.CodeOrderNotFound},
+		{&pages.BrokerError{Code: pages.CodeAmbiguousKey}, http.StatusConflict, pages.CodeAmbiguousKey},
+		{&pages.BrokerError{Code: pages.CodeRejected}, http.StatusBadGateway, pages.CodeRejected},
+		{&pages.BrokerError{Code: "unknown"}, http.StatusInternalServerError, "unknown"},
+		{pages.ErrTwoFactorRequired, http.StatusServiceUnavailable, pages.ErrTwoFactorRequired.Code},
+		{fmt.Errorf("driver failed"), http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		status, code := brokerStatus(tt.err)
+		if status != tt.status || code != tt.code {
+			t.Errorf("%v: got %d %q, want %d %q", tt.err, status, code, tt.status, tt.code)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"trading/migrations"
+	"trading/pages"
+	"trading/repository"
+)
+
+// newTestHandler returns a handler of an account stored in a fresh sqlite
+// database with the positions kept by the memory broker
+func newTestHandler(t *testing.T, broker pages.Broker) *Handler {
+	t.Helper()
+	db, err := repository.Open(repository.SQLite, filepath.Join(t.TempDir(), "trading.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	if broker == nil {
+		broker = pages.NewMemoryBroker()
+	}
+	return &Handler{Account: "test", Items: db.Items("test"), Orders: db.Orders("test"), Broker: broker}
+}
+
+// serve runs a request through the handler function with the route variables
+func serve(handler func(w http.ResponseWriter, r *http.Request), method, url, body string, vars map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+	if vars != nil {
+		r = mux.SetURLVars(r, vars)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// stubBroker returns the positions of the test from ListPositions
+type stubBroker struct {
+	pages.Broker
+	positions map[string]*pages.Position
+	err       error
+}
+
+func (b *stubBroker) ListPositions() (map[string]*pages.Position, error) {
+	return b.positions, b.err
+}
+
+func (b *stubBroker) GetPositions(ids []string) (map[string]*pages.Position, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	positions := make(map[string]*pages.Position, len(ids))
+	for _, id := range ids {
+		if position, ok := b.positions[id]; ok {
+			positions[id] = position
+		}
+	}
+	return positions, nil
+}
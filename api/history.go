@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"time"
+	"trading/repository"
+)
+
+// historyDate is the format of the date filters
+const historyDate = "2006-01-02"
+
+// parseHistoryDate accepts a date or a RFC3339 time,
+// a date of the upper bound includes the whole day
+func parseHistoryDate(value string, upper bool) (time.Time, error) {
+	if t, err := time.Parse(historyDate, value); err == nil {
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GetHistory returns closed positions
+// @Summary Get closed positions
+// @Description Get closed positions filtered by the close date and the instrument
+// @Tags positions
+// @Produce json
+// @Param from query string false "Closed at or after (2006-01-02 or RFC3339)"
+// @Param to query string false "Closed before or on (2006-01-02 or RFC3339)"
+// @Param instrument query string false "Instrument"
+// @Success 200 {array} repository.HistoryItem
+// @Failure 400 {object} ValidationErrors
+// @Router /accounts/{name}/positions/history [get]
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := repository.HistoryFilter{Instrument: query.Get("instrument")}
+
+	errs := ValidationErrors{}
+	if from := query.Get("from"); from != "" {
+		t, err := parseHistoryDate(from, false)
+		if err != nil {
+			errs.add("from", "must be a date (2006-01-02) or RFC3339 time")
+		}
+		filter.From = t
+	}
+	if to := query.Get("to"); to != "" {
+		t, err := parseHistoryDate(to, true)
+		if err != nil {
+			errs.add("to", "must be a date (2006-01-02) or RFC3339 time")
+		}
+		filter.To = t
+	}
+	if len(errs) != 0 {
+		respondWithValidation(w, errs)
+		return
+	}
+
+	items, err := h.Items.History(filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, items)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"trading/pages"
+	"trading/repository"
+)
+
+// ResolveIntents settles the positions which were left pending or executing by
+// a crash. An executing intent is confirmed with the only unknown position of the
+// platform which has the same instrument, direction and quantity. Only the
+// intents up to lastID are settled, the later ones belong to running requests.
+func (h *Handler) ResolveIntents(lastID int64) error {
+	intents, err := h.Items.Unresolved(lastID)
+	if err != nil {
+		return err
+	}
+	if len(intents) == 0 {
+		return nil
+	}
+	known, err := h.Items.KnownGUIDs()
+	if err != nil {
+		return err
+	}
+	positions, err := h.Broker.ListPositions()
+	if err != nil {
+		return err
+	}
+
+	for _, intent := range intents {
+		fields := log.Fields{"account": h.Account, "id": intent.ID, "instrument": intent.Instrument}
+		// the browser hasn't been touched for a pending intent
+		if intent.Intent == repository.IntentPending {
+			err = h.Items.SetIntent(intent.ID, repository.IntentFailed, "not executed")
+			if err != nil {
+				return err
+			}
+			log.WithFields(fields).Warn("Pending intent is failed")
+			continue
+		}
+
+		candidates := make([]string, 0)
+		for guid, position := range positions {
+			if !known[guid] && matchIntent(intent, position) {
+				candidates = append(candidates, guid)
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			err = h.Items.SetIntent(intent.ID, repository.IntentFailed, "position is not found on the platform")
+			if err != nil {
+				return err
+			}
+			log.WithFields(fields).Warn("Executing intent is failed")
+		case 1:
+			guid := candidates[0]
+			position := positions[guid]
+			item := &pages.Item{Key: guid, Qty: position.Quantity, Price: position.Price}
+			err = h.Items.Confirm(intent.ID, item)
+			if err != nil {
+				return err
+			}
+			known[guid] = true
+			log.WithFields(fields).Infof("Executing intent is confirmed with %s", guid)
+		default:
+			// it has to be resolved by hand, a wrong guid is worse than none
+			log.WithFields(fields).Error(fmt.Sprintf("Executing intent matches %d positions: %s",
+				len(candidates), strings.Join(candidates, ", ")))
+		}
+	}
+	return nil
+}
+
+func matchIntent(intent *repository.Intent, position *pages.Position) bool {
+	return strings.EqualFold(intent.Instrument, position.Instrument) &&
+		strings.EqualFold(intent.Direction, position.Direction) &&
+		intent.Qty == position.Quantity
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"testing"
+	"trading/pages"
+	"trading/repository"
+)
+
+func TestResolveIntents(t *testing.T) {
+	broker := pages.NewMemoryBroker()
+	h := newTestHandler(t, broker)
+	item := &pages.Item{Instrument: "AAPL", Direction: pages.BUY, Qty: 1}
+
+	// a pending intent is left by the earlier versions, the browser wasn't touched for it
+	pending, err := h.Items.Intend(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Items.SetIntent(pending, repository.IntentPending, ""); err != nil {
+		t.Fatal(err)
+	}
+	// the position of the executing intent was opened before the crash
+	executing, err := h.Items.Intend(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := broker.Add(&pages.Item{Instrument: "AAPL", Direction: pages.BUY, Qty: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.ResolveIntents(executing); err != nil {
+		t.Fatal(err)
+	}
+	intents, err := h.Items.Unresolved(executing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(intents) != 0 {
+		t.Fatalf("unresolved intents: %+v", intents)
+	}
+	if guid, err := h.Items.FindGUID(int(executing)); err != nil || guid != opened.Key {
+		t.Errorf("guid of the executing intent is %q, want %q: %v", guid, opened.Key, err)
+	}
+	if guid, err := h.Items.FindGUID(int(pending)); err != nil || guid != "" {
+		t.Errorf("failed intent has the guid %q: %v", guid, err)
+	}
+}
+
+func TestResolveIntentsSkipsLaterIntents(t *testing.T) {
+	h := newTestHandler(t, nil)
+	item := &pages.Item{Instrument: "AAPL", Direction: pages.BUY, Qty: 1}
+	crashed, err := h.Items.Intend(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastID, err := h.Items.LastID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastID != crashed {
+		t.Fatalf("last id is %d, want %d", lastID, crashed)
+	}
+	// a request of the new run is in flight
+	running, err := h.Items.Intend(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.ResolveIntents(lastID); err != nil {
+		t.Fatal(err)
+	}
+	intents, err := h.Items.Unresolved(running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(intents) != 1 || intents[0].ID != running || intents[0].Intent != repository.IntentExecuting {
+		t.Fatalf("unresolved intents: %+v", intents)
+	}
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"trading/pages"
+)
+
+// missedPasses is the number of the passes a position has to be missing from the
+// platform in a row before it's closed, one broken scrape doesn't close anything
+const missedPasses = 2
+
+// Report is the result of a reconciliation pass
+type Report struct {
+	At time.Time `json:"at"`
+	// Closed are the items which have vanished from the platform and are closed now
+	Closed []*ReportItem `json:"closed"`
+	// Missing are the items which are not found on the platform for the first time
+	Missing []*ReportItem `json:"missing"`
+	// Untracked are the positions opened outside of the API, they can be adopted
+	Untracked []*UntrackedPosition `json:"untracked"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// ReportItem is an item of the reconciliation report
+type ReportItem struct {
+	ID         int    `json:"id"`
+	GUID       string `json:"guid"`
+	Instrument string `json:"instrument"`
+	Direction  string `json:"direction"`
+	Quantity   int    `json:"quantity"`
+}
+
+// UntrackedPosition is a position of the platform which is not in the items
+type UntrackedPosition struct {
+	GUID string `json:"guid"`
+	*pages.Position
+}
+
+// Reconciler compares the items with the positions of the platform
+type Reconciler struct {
+	handler *Handler
+	mu      sync.Mutex
+	report  *Report
+	missed  map[string]int
+}
+
+// NewReconciler creates a reconciler of the account of the handler
+func NewReconciler(handler *Handler) *Reconciler {
+	return &Reconciler{handler: handler, missed: make(map[string]int, 0)}
+}
+
+// Reconcile runs a pass and keeps its report
+func (rc *Reconciler) Reconcile() (*Report, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	report, err := rc.reconcile()
+	if err != nil {
+		report.Error = err.Error()
+		rc.handler.logger().Error("Reconciliation failed: ", err)
+	}
+	rc.report = report
+	return report, err
+}
+
+func (rc *Reconciler) reconcile() (*Report, error) {
+	h := rc.handler
+	report := &Report{
+		At:        time.Now().UTC(),
+		Closed:    make([]*ReportItem, 0),
+		Missing:   make([]*ReportItem, 0),
+		Untracked: make([]*UntrackedPosition, 0),
+	}
+	// the items are read first, a position opened during the pass is only
+	// reported as untracked
+	items, err := h.Items.ListOpen()
+	if err != nil {
+		return report, err
+	}
+	known, err := h.Items.KnownGUIDs()
+	if err != nil {
+		return report, err
+	}
+	positions, err := h.Broker.ListPositions()
+	if err != nil {
+		return report, err
+	}
+
+	missed := make(map[string]int, 0)
+	for _, item := range items {
+		if _, ok := positions[item.GUID]; ok {
+			continue
+		}
+		reportItem := &ReportItem{ID: item.ID, GUID: item.GUID, Instrument: item.Instrument, Direction: item.Dir, Quantity: item.Qty}
+		missed[item.GUID] = rc.missed[item.GUID] + 1
+		if missed[item.GUID] < missedPasses {
+			report.Missing = append(report.Missing, reportItem)
+			continue
+		}
+		err = h.Items.CloseExternally(item.ID)
+		if err != nil {
+			return report, err
+		}
+		delete(missed, item.GUID)
+		report.Closed = append(report.Closed, reportItem)
+		rc.handler.logger().WithFields(log.Fields{"id": item.ID, "guid": item.GUID}).Warn("Position is closed outside of the API")
+	}
+	rc.missed = missed
+
+	for guid, position := range positions {
+		if !known[guid] {
+			report.Untracked = append(report.Untracked, &UntrackedPosition{GUID: guid, Position: position})
+		}
+	}
+	sort.Slice(report.Untracked, func(i, j int) bool {
+		return report.Untracked[i].GUID < report.Untracked[j].GUID
+	})
+	rc.handler.logger().Debug(fmt.Sprintf("Reconciled: %d closed, %d missing, %d untracked",
+		len(report.Closed), len(report.Missing), len(report.Untracked)))
+	return report, nil
+}
+
+// Run reconciles with the interval until the stop channel is closed
+func (rc *Reconciler) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		rc.Reconcile()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetReport returns the last reconciliation report
+// @Summary Get the reconciliation report
+// @Description Compare the items with the positions of the platform, run=true runs a new pass
+// @Tags reconciliation
+// @Produce json
+// @Param run query bool false "Run a new pass"
+// @Success 200 {object} Report
+// @Router /accounts/{name}/reconciliation [get]
+func (rc *Reconciler) GetReport(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("run") == "true" {
+		report, err := rc.Reconcile()
+		if err != nil {
+			respondWithBrokerError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+
+	rc.mu.Lock()
+	report := rc.report
+	rc.mu.Unlock()
+	if report == nil {
+		respondWithError(w, http.StatusNotFound, "Reconciliation hasn't run yet")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// Adopt stores a position opened outside of the API in the items
+// @Summary Adopt an untracked position
+// @Description Store a position opened outside of the API in the items
+// @Tags reconciliation
+// @Produce json
+// @Param guid path string true "Position guid"
+// @Success 200 {object} Response
+// @Router /accounts/{name}/reconciliation/adopt/{guid} [post]
+func (rc *Reconciler) Adopt(w http.ResponseWriter, r *http.Request) {
+	h := rc.handler
+	guid := mux.Vars(r)["guid"]
+
+	known, err := h.Items.KnownGUIDs()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if known[guid] {
+		respondWithError(w, http.StatusConflict, fmt.Sprintf("Position %s is already tracked", guid))
+		return
+	}
+	position, err := h.Broker.GetPosition(guid)
+	if err != nil {
+		respondWithBrokerError(w, err)
+		return
+	}
+	id, err := h.Items.Adopt(guid, position)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rc.handler.logger().WithFields(log.Fields{"id": id, "guid": guid}).Info("Position is adopted")
+	response := &Response{ID: id, Message: "Position is adopted", Status: Success}
+	respondWithJSON(w, http.StatusOK, response)
+}
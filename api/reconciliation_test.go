@@ -0,0 +1,130 @@
+package api
+
+import (
+	"testing"
+	"trading/pages"
+	"trading/repository"
+)
+
+// openItem stores a confirmed position with the guid
+func openItem(t *testing.T, items repository.ItemRepository, guid string) int64 {
+	t.Helper()
+	item := &pages.Item{Instrument: "AAPL", Direction: pages.BUY, Qty: 1, Key: guid}
+	id, err := items.Intend(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := items.Confirm(id, item); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func position() *pages.Position {
+	return &pages.Position{BasePosition: pages.BasePosition{Instrument: "AAPL", Direction: pages.BUY, Quantity: 1}}
+}
+
+func openCount(t *testing.T, items repository.ItemRepository) int {
+	t.Helper()
+	open, err := items.ListOpen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(open)
+}
+
+func TestReconcileClosesAfterMissedPasses(t *testing.T) {
+	broker := &stubBroker{positions: map[string]*pages.Position{"1": position(), "2": position()}}
+	h := newTestHandler(t, broker)
+	openItem(t, h.Items, "1")
+	openItem(t, h.Items, "2")
+	rc := NewReconciler(h)
+
+	broker.positions = map[string]*pages.Position{"2": position()}
+	report, err := rc.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Missing) != 1 || len(report.Closed) != 0 {
+		t.Fatalf("first pass: %d missing, %d closed", len(report.Missing), len(report.Closed))
+	}
+
+	report, err = rc.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Closed) != 1 || report.Closed[0].GUID != "1" {
+		t.Fatalf("second pass: closed %v", report.Closed)
+	}
+	if n := openCount(t, h.Items); n != 1 {
+		t.Fatalf("%d items are open, want 1", n)
+	}
+}
+
+func TestReconcileResetsCounterWhenPositionReappears(t *testing.T) {
+	broker := &stubBroker{positions: map[string]*pages.Position{"1": position(), "2": position()}}
+	h := newTestHandler(t, broker)
+	openItem(t, h.Items, "1")
+	openItem(t, h.Items, "2")
+	rc := NewReconciler(h)
+
+	missing := map[string]*pages.Position{"2": position()}
+	all := map[string]*pages.Position{"1": position(), "2": position()}
+	for _, positions := range []map[string]*pages.Position{missing, all, missing} {
+		broker.positions = positions
+		report, err := rc.Reconcile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Closed) != 0 {
+			t.Fatalf("closed %v, a single miss must not close", report.Closed)
+		}
+	}
+	if n := openCount(t, h.Items); n != 2 {
+		t.Fatalf("%d items are open, want 2", n)
+	}
+}
+
+func TestReconcileClosesLastPosition(t *testing.T) {
+	broker := &stubBroker{positions: map[string]*pages.Position{"1": position()}}
+	h := newTestHandler(t, broker)
+	openItem(t, h.Items, "1")
+	rc := NewReconciler(h)
+
+	// the last stop loss has fired, the table is empty
+	broker.positions = map[string]*pages.Position{}
+	report, err := rc.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Missing) != 1 || len(report.Closed) != 0 {
+		t.Fatalf("first pass: %d missing, %d closed", len(report.Missing), len(report.Closed))
+	}
+
+	report, err = rc.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Closed) != 1 || report.Closed[0].GUID != "1" {
+		t.Fatalf("second pass: closed %v", report.Closed)
+	}
+	if n := openCount(t, h.Items); n != 0 {
+		t.Fatalf("%d items are open, want 0", n)
+	}
+}
+
+func TestReconcileKeepsItemsOnFailedScrape(t *testing.T) {
+	broker := &stubBroker{err: pages.ErrSessionExpired}
+	h := newTestHandler(t, broker)
+	openItem(t, h.Items, "1")
+	rc := NewReconciler(h)
+
+	for i := 0; i < 3; i++ {
+		if _, err := rc.Reconcile(); err == nil {
+			t.Fatal("failed scrape is not an error")
+		}
+	}
+	if n := openCount(t, h.Items); n != 1 {
+		t.Fatalf("%d items are open, want 1", n)
+	}
+}
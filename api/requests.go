@@ -0,0 +1,350 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"trading/pages"
+)
+
+// AddRequest is a body of POST /positions and POST /orders
+type AddRequest struct {
+	Instrument string         `json:"instrument"`
+	Direction  string         `json:"direction"`
+	Qty        int            `json:"qty"`
+	Price      float64        `json:"price"`
+	IsOrder    *bool          `json:"is_order"`
+	Limits     *LimitsRequest `json:"limits"`
+}
+
+// LimitsRequest contains take-profit and stop-loss of a new position
+type LimitsRequest struct {
+	TP *pages.Limit `json:"tp"`
+	SL *pages.Limit `json:"sl"`
+}
+
+// EditRequest is a body of PUT /positions/{id}
+type EditRequest struct {
+	Quantity *pages.PositionPayload `json:"quantity"`
+}
+
+// EditLimitsRequest is the body of PUT /positions/{id}/limits,
+// an omitted limit is left as it is
+type EditLimitsRequest struct {
+	TakeProfit   *pages.Limit `json:"take_profit"`
+	StopLoss     *pages.Limit `json:"stop_loss"`
+	TrailingStop *pages.Limit `json:"trailing_stop"`
+}
+
+// CloseRequest is the body of POST /positions/{id}/close,
+// exactly one of the fields has to be set
+type CloseRequest struct {
+	Quantity       int     `json:"quantity"`
+	Percent        float64 `json:"percent"`
+	TargetQuantity *int    `json:"target_quantity"`
+}
+
+// FieldError describes an invalid field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is a list of invalid fields
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationErrors) add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+// decodeStrict decodes a single JSON object rejecting unknown fields
+func decodeStrict(body io.Reader, dst interface{}) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(dst)
+	if err == nil && dec.More() {
+		return ValidationErrors{{Message: "body must contain a single JSON object"}}
+	}
+	if err == nil {
+		return nil
+	}
+
+	switch e := err.(type) {
+	case *json.UnmarshalTypeError:
+		return ValidationErrors{{Field: e.Field, Message: fmt.Sprintf("must be %s", e.Type)}}
+	case *json.SyntaxError:
+		return ValidationErrors{{Message: fmt.Sprintf("malformed JSON at position %d", e.Offset)}}
+	}
+	if err == io.EOF {
+		return ValidationErrors{{Message: "body is empty"}}
+	}
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return ValidationErrors{{Field: field, Message: "unknown field"}}
+	}
+	return ValidationErrors{{Message: err.Error()}}
+}
+
+// validate checks the request of a new position or a pending order
+func (req *AddRequest) validate(isOrder bool) ValidationErrors {
+	errs := ValidationErrors{}
+	if strings.TrimSpace(req.Instrument) == "" {
+		errs.add("instrument", "is required")
+	}
+	if req.Direction != pages.BUY && req.Direction != pages.SELL {
+		errs.add("direction", fmt.Sprintf("must be %s or %s", pages.BUY, pages.SELL))
+	}
+	if req.Qty <= 0 {
+		errs.add("qty", "must be greater than 0")
+	}
+	if req.IsOrder != nil && *req.IsOrder != isOrder {
+		if isOrder {
+			errs.add("is_order", "must be true for orders")
+		} else {
+			errs.add("is_order", "orders are created by POST /orders")
+		}
+	}
+	if isOrder && req.Price <= 0 {
+		errs.add("price", "is required for orders and must be greater than 0")
+	}
+	if !isOrder && req.Price < 0 {
+		errs.add("price", "must not be negative")
+	}
+	if req.Limits != nil {
+		validateLimit(&errs, "limits.tp", req.Limits.TP)
+		validateLimit(&errs, "limits.sl", req.Limits.SL)
+		if len(errs) == 0 {
+			validateLimitPrices(&errs, "limits.tp", "limits.sl", req.Direction, req.Price, req.Limits.TP, req.Limits.SL)
+		}
+	}
+	return errs
+}
+
+// validateLimit checks that a used limit is set in exactly one way
+func validateLimit(errs *ValidationErrors, field string, limit *pages.Limit) {
+	if limit == nil {
+		return
+	}
+	set := 0
+	for _, value := range []float64{limit.Price, limit.Distance, limit.Result} {
+		if value != 0 {
+			set++
+		}
+	}
+	if !limit.IsUse {
+		if set != 0 {
+			errs.add(field+".is_use", "must be true when a value is set")
+		}
+		return
+	}
+	if set != 1 {
+		errs.add(field, "exactly one of price, distance or result must be set")
+	}
+	if limit.Price < 0 {
+		errs.add(field+".price", "must be greater than 0")
+	}
+	if limit.Distance < 0 {
+		errs.add(field+".distance", "must be greater than 0")
+	}
+}
+
+// validateLimitPrices checks that take-profit and stop-loss are on the right sides
+// of the entry price and of each other, a distance is counted from the price if it's known
+func validateLimitPrices(errs *ValidationErrors, tpField, slField, direction string, price float64, tp, sl *pages.Limit) {
+	tpPrice := limitPrice(errs, tpField, tp, price, direction == pages.BUY)
+	slPrice := limitPrice(errs, slField, sl, price, direction != pages.BUY)
+	// a buy position profits when the price goes up, a sell one when it goes down
+	above := func(a, b float64) bool {
+		if direction == pages.BUY {
+			return a > b
+		}
+		return a < b
+	}
+	side, slSide := "above", "below"
+	if direction == pages.SELL {
+		side, slSide = slSide, side
+	}
+	if price > 0 && tpPrice > 0 && !above(tpPrice, price) {
+		errs.add(tpField+".price", fmt.Sprintf("must be %s the price for %s", side, direction))
+	}
+	if price > 0 && slPrice > 0 && !above(price, slPrice) {
+		errs.add(slField+".price", fmt.Sprintf("must be %s the price for %s", slSide, direction))
+	}
+	if tpPrice > 0 && slPrice > 0 && !above(tpPrice, slPrice) {
+		errs.add("limits", fmt.Sprintf("take-profit must be %s stop-loss for %s", side, direction))
+	}
+}
+
+// limitPrice returns the price of a used limit, it's 0 if the price isn't known.
+// The distance is added to the price if up is true and subtracted otherwise.
+func limitPrice(errs *ValidationErrors, field string, limit *pages.Limit, price float64, up bool) float64 {
+	if limit == nil || !limit.IsUse {
+		return 0
+	}
+	if limit.Price > 0 || limit.Distance <= 0 || price <= 0 {
+		return limit.Price
+	}
+	if up {
+		return price + limit.Distance
+	}
+	if limit.Distance >= price {
+		errs.add(field+".distance", "must be less than the price")
+		return 0
+	}
+	return price - limit.Distance
+}
+
+// item converts the request to an item of the broker
+func (req *AddRequest) item(isOrder bool) *pages.Item {
+	item := &pages.Item{
+		Instrument: req.Instrument,
+		Direction:  req.Direction,
+		Qty:        req.Qty,
+		Price:      req.Price,
+		IsOrder:    isOrder,
+		Limits: map[string]*pages.Limit{
+			"tp": {},
+			"sl": {},
+		},
+	}
+	if req.Limits != nil {
+		if req.Limits.TP != nil {
+			item.Limits["tp"] = req.Limits.TP
+		}
+		if req.Limits.SL != nil {
+			item.Limits["sl"] = req.Limits.SL
+		}
+	}
+	return item
+}
+
+// validate checks the request of the position editing
+func (req *EditRequest) validate() ValidationErrors {
+	errs := ValidationErrors{}
+	payload := req.Quantity
+	if payload == nil {
+		errs.add("quantity", "is required")
+		return errs
+	}
+	if payload.Direction != pages.BUY && payload.Direction != pages.SELL {
+		errs.add("quantity.direction", fmt.Sprintf("must be %s or %s", pages.BUY, pages.SELL))
+	}
+	if payload.IsPercent {
+		if payload.Percent <= 0 || payload.Percent > 100 {
+			errs.add("quantity.percent", "must be greater than 0 and not greater than 100")
+		}
+		if payload.Value != 0 {
+			errs.add("quantity.value", "must not be set with is_percent")
+		}
+	} else {
+		if payload.Value <= 0 {
+			errs.add("quantity.value", "must be greater than 0")
+		}
+		if payload.Percent != 0 {
+			errs.add("quantity.percent", "is only used with is_percent")
+		}
+	}
+	return errs
+}
+
+func (req *CloseRequest) validate() ValidationErrors {
+	errs := ValidationErrors{}
+	set := 0
+	if req.Quantity != 0 {
+		set++
+		if req.Quantity < 0 {
+			errs.add("quantity", "must be greater than 0")
+		}
+	}
+	if req.Percent != 0 {
+		set++
+		if req.Percent < 0 || req.Percent > 100 {
+			errs.add("percent", "must be greater than 0 and not greater than 100")
+		}
+	}
+	if req.TargetQuantity != nil {
+		set++
+		if *req.TargetQuantity < 0 {
+			errs.add("target_quantity", "must not be negative")
+		}
+	}
+	if set != 1 {
+		errs.add("close", "exactly one of quantity, percent or target_quantity must be set")
+	}
+	return errs
+}
+
+func (req *CloseRequest) payload() *pages.ClosePayload {
+	return &pages.ClosePayload{
+		Quantity:       req.Quantity,
+		Percent:        req.Percent,
+		TargetQuantity: req.TargetQuantity,
+	}
+}
+
+// validate checks the limits of a position in the given direction
+func (req *EditLimitsRequest) validate(direction string) ValidationErrors {
+	errs := ValidationErrors{}
+	if req.TakeProfit == nil && req.StopLoss == nil && req.TrailingStop == nil {
+		errs.add("limits", "at least one of take_profit, stop_loss or trailing_stop is required")
+		return errs
+	}
+	validateLimit(&errs, "take_profit", req.TakeProfit)
+	validateLimit(&errs, "stop_loss", req.StopLoss)
+	if ts := req.TrailingStop; ts != nil {
+		if ts.Price != 0 || ts.Result != 0 {
+			errs.add("trailing_stop", "only distance can be set")
+		} else if ts.IsUse && ts.Distance <= 0 {
+			errs.add("trailing_stop.distance", "must be greater than 0")
+		}
+		if !ts.IsUse && ts.Distance != 0 {
+			errs.add("trailing_stop.is_use", "must be true when a value is set")
+		}
+	}
+	validateLimitPrices(&errs, "take_profit", "stop_loss", direction, 0, req.TakeProfit, req.StopLoss)
+	return errs
+}
+
+func (req *EditLimitsRequest) payload() *pages.LimitsPayload {
+	return &pages.LimitsPayload{
+		TakeProfit:   req.TakeProfit,
+		StopLoss:     req.StopLoss,
+		TrailingStop: req.TrailingStop,
+	}
+}
+
+// decodeAdd reads and validates the request of a new position or order
+func decodeAdd(w http.ResponseWriter, r *http.Request, isOrder bool) (*pages.Item, bool) {
+	defer r.Body.Close()
+	req := &AddRequest{}
+	if err := decodeStrict(r.Body, req); err != nil {
+		respondWithValidation(w, err)
+		return nil, false
+	}
+	if errs := req.validate(isOrder); len(errs) != 0 {
+		respondWithValidation(w, errs)
+		return nil, false
+	}
+	return req.item(isOrder), true
+}
+
+func respondWithValidation(w http.ResponseWriter, err error) {
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		errs = ValidationErrors{{Message: err.Error()}}
+	}
+	respondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"error":  "Invalid request",
+		"fields": errs,
+	})
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"trading/pages"
+)
+
+// fields returns the sorted fields of the errors
+func fields(errs ValidationErrors) []string {
+	names := make([]string, 0, len(errs))
+	for _, err := range errs {
+		names = append(names, err.Field)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkFields(t *testing.T, name string, errs ValidationErrors, want []string) {
+	t.Helper()
+	got := fields(errs)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("%s: invalid fields %v, want %v (%s)", name, got, want, errs)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		field string
+		ok    bool
+	}{
+		{"valid", `{"instrument": "AAPL", "direction": "buy", "qty": 1}`, "", true},
+		{"unknown field", `{"instrument": "AAPL", "quantity": 1}`, "quantity", false},
+		{"unknown nested field", `{"limits": {"tp": {"is_use": true, "value": 1}}}`, "value", false},
+		{"wrong type", `{"qty": "1"}`, "qty", false},
+		{"malformed", `{"qty": 1`, "", false},
+		{"empty", ``, "", false},
+		{"two objects", `{"qty": 1} {"qty": 2}`, "", false},
+	}
+	for _, tt := range tests {
+		err := decodeStrict(strings.NewReader(tt.body), &AddRequest{})
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%s: %s", tt.name, err)
+			}
+			continue
+		}
+		errs, ok := err.(ValidationErrors)
+		if !ok || len(errs) != 1 {
+			t.Errorf("%s: got %v, want a validation error", tt.name, err)
+			continue
+		}
+		if errs[0].Field != tt.field {
+			t.Errorf("%s: field %q, want %q", tt.name, errs[0].Field, tt.field)
+		}
+	}
+}
+
+func TestAddRequestValidate(t *testing.T) {
+	price := func(value float64) *pages.Limit { return &pages.Limit{IsUse: true, Price: value} }
+	distance := func(value float64) *pages.Limit { return &pages.Limit{IsUse: true, Distance: value} }
+	tests := []struct {
+		name    string
+		req     AddRequest
+		isOrder bool
+		fields  []string
+	}{
+		{"valid position", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1}, false, []string{}},
+		{"missing instrument", AddRequest{Instrument: " ", Direction: pages.BUY, Qty: 1}, false, []string{"instrument"}},
+		{"missing direction", AddRequest{Instrument: "AAPL", Qty: 1}, false, []string{"direction"}},
+		{"wrong direction", AddRequest{Instrument: "AAPL", Direction: "BUY", Qty: 1}, false, []string{"direction"}},
+		{"zero qty", AddRequest{Instrument: "AAPL", Direction: pages.SELL}, false, []string{"qty"}},
+		{"order without price", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1}, true, []string{"price"}},
+		{"limit set twice", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1,
+			Limits: &LimitsRequest{TP: &pages.Limit{IsUse: true, Price: 110, Distance: 5}}}, false, []string{"limits.tp"}},
+		{"unused limit with value", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1,
+			Limits: &LimitsRequest{SL: &pages.Limit{Price: 90}}}, false, []string{"limits.sl.is_use"}},
+
+		{"buy limits by price", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1, Price: 100,
+			Limits: &LimitsRequest{TP: price(110), SL: price(90)}}, false, []string{}},
+		{"buy take-profit below the price", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1, Price: 100,
+			Limits: &LimitsRequest{TP: price(95)}}, false, []string{"limits.tp.price"}},
+		{"sell stop-loss below the price", AddRequest{Instrument: "AAPL", Direction: pages.SELL, Qty: 1, Price: 100,
+			Limits: &LimitsRequest{SL: price(95)}}, false, []string{"limits.sl.price"}},
+		{"take-profit below stop-loss", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1,
+			Limits: &LimitsRequest{TP: price(90), SL: price(95)}}, false, []string{"limits"}},
+
+		{"buy limits by distance", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1, Price: 100,
+			Limits: &LimitsRequest{TP: distance(10), SL: distance(5)}}, false, []string{}},
+		{"buy stop-loss price above take-profit distance", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1, Price: 100,
+			Limits: &LimitsRequest{TP: distance(5), SL: price(110)}}, false, []string{"limits", "limits.sl.price"}},
+		{"sell take-profit price above stop-loss distance", AddRequest{Instrument: "AAPL", Direction: pages.SELL, Qty: 1, Price: 100,
+			Limits: &LimitsRequest{TP: price(108), SL: distance(5)}}, false, []string{"limits", "limits.tp.price"}},
+		{"buy stop-loss distance beyond the price", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1, Price: 100,
+			Limits: &LimitsRequest{SL: distance(100)}}, false, []string{"limits.sl.distance"}},
+		{"distance without the price", AddRequest{Instrument: "AAPL", Direction: pages.BUY, Qty: 1,
+			Limits: &LimitsRequest{TP: distance(5), SL: price(110)}}, false, []string{}},
+	}
+	for _, tt := range tests {
+		checkFields(t, tt.name, tt.req.validate(tt.isOrder), tt.fields)
+	}
+}
+
+func TestEditRequestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    EditRequest
+		fields []string
+	}{
+		{"valid value", EditRequest{Quantity: &pages.PositionPayload{Direction: pages.BUY, Value: 2}}, []string{}},
+		{"valid percent", EditRequest{Quantity: &pages.PositionPayload{Direction: pages.SELL, IsPercent: true, Percent: 50}}, []string{}},
+		{"missing quantity", EditRequest{}, []string{"quantity"}},
+		{"missing direction", EditRequest{Quantity: &pages.PositionPayload{Value: 2}}, []string{"quantity.direction"}},
+		{"zero value", EditRequest{Quantity: &pages.PositionPayload{Direction: pages.BUY}}, []string{"quantity.value"}},
+		{"percent over 100", EditRequest{Quantity: &pages.PositionPayload{Direction: pages.BUY, IsPercent: true, Percent: 120}},
+			[]string{"quantity.percent"}},
+		{"value with percent", EditRequest{Quantity: &pages.PositionPayload{Direction: pages.BUY, IsPercent: true, Percent: 10, Value: 1}},
+			[]string{"quantity.value"}},
+	}
+	for _, tt := range tests {
+		checkFields(t, tt.name, tt.req.validate(), tt.fields)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+	"trading/pages"
+)
+
+// Snapshot is the state of all opened positions of the platform at a moment
+type Snapshot struct {
+	AsOf      time.Time
+	Positions map[string]*pages.Position
+}
+
+// PositionCache keeps the last snapshot of the positions, so the requests
+// don't wait for the browser
+type PositionCache struct {
+	broker pages.Broker
+	maxAge time.Duration
+
+	mu       sync.RWMutex
+	snapshot *Snapshot
+	err      error
+	// generation is bumped by Forget, forgotten keeps the generation of
+	// the forgotten guids, so a refresh running meanwhile drops them
+	generation uint64
+	forgotten  map[string]uint64
+}
+
+// PositionsResponse is the positions with the age of the data
+type PositionsResponse struct {
+	AsOf time.Time `json:"as_of"`
+	// Age is the age of the data in seconds
+	Age   float64 `json:"age"`
+	Stale bool    `json:"stale"`
+	// Error is the error of the last refresh of the cache or of the platform
+	Error     string            `json:"error,omitempty"`
+	Positions []*pages.Position `json:"positions"`
+	// Missing are the ids of the opened positions which aren't found on the platform
+	Missing []int `json:"missing,omitempty"`
+}
+
+// NewPositionCache creates a cache, a snapshot older than maxAge is stale
+func NewPositionCache(broker pages.Broker, maxAge time.Duration) *PositionCache {
+	return &PositionCache{broker: broker, maxAge: maxAge}
+}
+
+// Refresh reads all positions of the platform, the last snapshot is kept on an error
+func (c *PositionCache) Refresh() error {
+	c.mu.RLock()
+	started := c.generation
+	c.mu.RUnlock()
+	asOf := time.Now().UTC()
+	positions, err := c.broker.ListPositions()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+	if err != nil {
+		return err
+	}
+	// the positions changed during the refresh might be read before the change
+	for guid, generation := range c.forgotten {
+		if generation > started {
+			delete(positions, guid)
+		} else {
+			delete(c.forgotten, guid)
+		}
+	}
+	c.snapshot = &Snapshot{AsOf: asOf, Positions: positions}
+	return nil
+}
+
+// Get returns the last snapshot and the error of the last refresh
+func (c *PositionCache) Get() (*Snapshot, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.snapshot, c.err
+}
+
+// Stale is true if the snapshot is older than the max age
+func (c *PositionCache) Stale(snapshot *Snapshot) bool {
+	return time.Since(snapshot.AsOf) > c.maxAge
+}
+
+// Forget drops a changed position, it's read from the platform until the next refresh
+func (c *PositionCache) Forget(guid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if c.forgotten == nil {
+		c.forgotten = make(map[string]uint64, 0)
+	}
+	c.forgotten[guid] = c.generation
+	if c.snapshot == nil {
+		return
+	}
+	positions := make(map[string]*pages.Position, len(c.snapshot.Positions))
+	for key, position := range c.snapshot.Positions {
+		if key != guid {
+			positions[key] = position
+		}
+	}
+	c.snapshot = &Snapshot{AsOf: c.snapshot.AsOf, Positions: positions}
+}
+
+// Run refreshes the cache with the interval until the stop channel is closed
+func (c *PositionCache) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Refresh(); err != nil {
+			log.Error("Positions snapshot is not refreshed: ", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
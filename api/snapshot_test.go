@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+	"trading/pages"
+)
+
+func TestRefreshKeepsSnapshotOnError(t *testing.T) {
+	broker := &stubBroker{positions: map[string]*pages.Position{"1": position(), "2": position()}}
+	cache := NewPositionCache(broker, time.Minute)
+	if err := cache.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	broker.err = pages.ErrSessionExpired
+	if err := cache.Refresh(); err == nil {
+		t.Fatal("failed refresh is not an error")
+	}
+	snapshot, err := cache.Get()
+	if err != pages.ErrSessionExpired {
+		t.Errorf("error of the refresh is %v", err)
+	}
+	if snapshot == nil || len(snapshot.Positions) != 2 {
+		t.Fatalf("last snapshot is not kept: %+v", snapshot)
+	}
+	if cache.Stale(snapshot) {
+		t.Error("fresh snapshot is stale")
+	}
+}
+
+func TestForgetDropsPosition(t *testing.T) {
+	broker := &stubBroker{positions: map[string]*pages.Position{"1": position(), "2": position()}}
+	cache := NewPositionCache(broker, time.Minute)
+	if err := cache.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := cache.Get()
+	cache.Forget("1")
+	snapshot, _ := cache.Get()
+	if _, ok := snapshot.Positions["1"]; ok {
+		t.Error("forgotten position is cached")
+	}
+	// the snapshot given out before isn't changed
+	if len(before.Positions) != 2 {
+		t.Errorf("%d positions in the earlier snapshot, want 2", len(before.Positions))
+	}
+}
+
+// slowBroker lists the positions when the release channel is closed
+type slowBroker struct {
+	pages.Broker
+	listing   chan struct{}
+	release   chan struct{}
+	positions map[string]*pages.Position
+}
+
+func (b *slowBroker) ListPositions() (map[string]*pages.Position, error) {
+	if b.listing != nil {
+		close(b.listing)
+		<-b.release
+	}
+	positions := make(map[string]*pages.Position, len(b.positions))
+	for guid, position := range b.positions {
+		positions[guid] = position
+	}
+	return positions, nil
+}
+
+func TestRefreshDropsPositionsForgottenMeanwhile(t *testing.T) {
+	broker := &slowBroker{
+		listing:   make(chan struct{}),
+		release:   make(chan struct{}),
+		positions: map[string]*pages.Position{"1": position(), "2": position()},
+	}
+	cache := NewPositionCache(broker, time.Minute)
+
+	done := make(chan error)
+	go func() { done <- cache.Refresh() }()
+	<-broker.listing
+	// the position is edited while the table is read
+	cache.Forget("1")
+	close(broker.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, _ := cache.Get()
+	if _, ok := snapshot.Positions["1"]; ok {
+		t.Error("position forgotten during the refresh is cached")
+	}
+	if _, ok := snapshot.Positions["2"]; !ok {
+		t.Error("position 2 is not cached")
+	}
+
+	// the next refresh has started after the change
+	broker.listing = nil
+	if err := cache.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, _ = cache.Get()
+	if len(snapshot.Positions) != 2 {
+		t.Errorf("%d positions are cached, want 2", len(snapshot.Positions))
+	}
+}
+
+func TestGetPositionsReportsMissing(t *testing.T) {
+	broker := pages.NewMemoryBroker()
+	h := newTestHandler(t, broker)
+	item, err := broker.Add(&pages.Item{Instrument: "AAPL", Direction: pages.BUY, Qty: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := openItem(t, h.Items, item.Key)
+	missing := openItem(t, h.Items, "gone")
+
+	rr := serve(h.GetPositions, "GET", "/accounts/test/positions", "", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	response := &PositionsResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Positions) != 1 || int64(response.Positions[0].ID) != found {
+		t.Errorf("positions: %+v", response.Positions)
+	}
+	if len(response.Missing) != 1 || int64(response.Missing[0]) != missing {
+		t.Errorf("missing: %v, want [%d]", response.Missing, missing)
+	}
+}
+
+func TestGetPositionsKeepsSnapshotOnBrokerError(t *testing.T) {
+	broker := &stubBroker{positions: map[string]*pages.Position{"1": position()}}
+	h := newTestHandler(t, broker)
+	h.Positions = NewPositionCache(broker, time.Minute)
+	openItem(t, h.Items, "1")
+	if err := h.Positions.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	// the position opened after the snapshot can't be read
+	openItem(t, h.Items, "2")
+	broker.err = pages.ErrSessionExpired
+
+	rr := serve(h.GetPositions, "GET", "/accounts/test/positions", "", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	response := &PositionsResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error == "" || len(response.Positions) != 1 || len(response.Missing) != 1 {
+		t.Errorf("response: %+v", response)
+	}
+
+	// there is nothing to return without the snapshot
+	h.Positions = nil
+	rr = serve(h.GetPositions, "GET", "/accounts/test/positions", "", nil)
+	if rr.Code == http.StatusOK {
+		t.Errorf("status %d without the snapshot", rr.Code)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/tebeka/selenium"
+	"github.com/tebeka/selenium/chrome"
+	"github.com/tebeka/selenium/firefox"
+	slog "github.com/tebeka/selenium/log"
+	"strings"
+	"time"
+	"trading/pages"
+)
+
+// the browsers of the selenium hub
+const (
+	chromeBrowser  = "chrome"
+	firefoxBrowser = "firefox"
+)
+
+// BrowserConfig is the browser which the accounts are opened in
+type BrowserConfig struct {
+	// Name is chrome or firefox, firefox is supported by the selenium backend only
+	Name string
+	// Path is the binary of the browser, the hub or chromedp find it if empty
+	Path string
+	// Args are added to the default switches of the command line
+	Args []string
+	// WindowWidth and WindowHeight are the size of the window, the default one is used if 0
+	WindowWidth  int
+	WindowHeight int
+	UserAgent    string
+	DownloadDir  string
+	// Proxy is host:port of the http and https proxy
+	Proxy   string
+	NoProxy []string
+	// PageLoadTimeout in seconds, 10 if it's 0
+	PageLoadTimeout int
+}
+
+// name returns the name of the browser, chrome by default
+func (b *BrowserConfig) name() string {
+	if b.Name == "" {
+		return chromeBrowser
+	}
+	return strings.ToLower(b.Name)
+}
+
+func (b *BrowserConfig) pageLoadTimeout() time.Duration {
+	if b.PageLoadTimeout <= 0 {
+		return time.Second * 10
+	}
+	return time.Duration(b.PageLoadTimeout) * time.Second
+}
+
+// chromeArgs returns the switches of the command line of Chrome
+func (b *BrowserConfig) chromeArgs() []string {
+	args := []string{
+		"--headless",
+		"--no-sandbox",
+	}
+	if b.WindowWidth > 0 && b.WindowHeight > 0 {
+		args = append(args, fmt.Sprintf("--window-size=%d,%d", b.WindowWidth, b.WindowHeight))
+	}
+	if b.UserAgent != "" {
+		args = append(args, "--user-agent="+b.UserAgent)
+	}
+	return append(args, b.Args...)
+}
+
+// firefoxArgs returns the switches of the command line of Firefox
+func (b *BrowserConfig) firefoxArgs() []string {
+	args := []string{"-headless"}
+	if b.WindowWidth > 0 && b.WindowHeight > 0 {
+		args = append(args, fmt.Sprintf("--width=%d", b.WindowWidth), fmt.Sprintf("--height=%d", b.WindowHeight))
+	}
+	return append(args, b.Args...)
+}
+
+// firefoxPrefs returns the preferences of Firefox, its user agent and
+// downloads are set there and not by the switches
+func (b *BrowserConfig) firefoxPrefs() map[string]interface{} {
+	prefs := make(map[string]interface{}, 0)
+	if b.UserAgent != "" {
+		prefs["general.useragent.override"] = b.UserAgent
+	}
+	if b.DownloadDir != "" {
+		prefs["browser.download.folderList"] = 2
+		prefs["browser.download.dir"] = b.DownloadDir
+		prefs["browser.download.useDownloadDir"] = true
+		prefs["browser.helperApps.neverAsk.saveToDisk"] = "application/octet-stream,application/pdf,text/csv"
+	}
+	return prefs
+}
+
+// capabilities returns the capabilities of the selenium session
+func (b *BrowserConfig) capabilities(networkLog bool) (selenium.Capabilities, error) {
+	caps := selenium.Capabilities(map[string]interface{}{
+		"browserName": b.name(),
+	})
+	if b.Proxy != "" {
+		caps.AddProxy(selenium.Proxy{Type: selenium.Manual, HTTP: b.Proxy, SSL: b.Proxy, NoProxy: b.NoProxy})
+	}
+
+	switch b.name() {
+	case chromeBrowser:
+		chromeCaps := chrome.Capabilities{
+			Path: b.Path,
+			Args: b.chromeArgs(),
+		}
+		if b.DownloadDir != "" {
+			chromeCaps.Prefs = map[string]interface{}{
+				"download.default_directory":   b.DownloadDir,
+				"download.prompt_for_download": false,
+			}
+		}
+		if networkLog {
+			enabled := true
+			chromeCaps.PerfLoggingPreferences = &chrome.PerfLoggingPreferences{EnableNetwork: &enabled}
+			caps.SetLogLevel(slog.Performance, slog.All)
+		}
+		caps.AddChrome(chromeCaps)
+	case firefoxBrowser:
+		if networkLog {
+			log.Warn("Network log is supported by Chrome only")
+		}
+		caps.AddFirefox(firefox.Capabilities{
+			Binary: b.Path,
+			Args:   b.firefoxArgs(),
+			Prefs:  b.firefoxPrefs(),
+		})
+	default:
+		return nil, fmt.Errorf("unknown browser: '%s'", b.Name)
+	}
+	return caps, nil
+}
+
+// openDriver starts the browser of the configured backend
+func openDriver() (pages.Driver, *pages.NetworkSource, error) {
+	browser := &config.Browser
+	switch config.BrowserBackend {
+	case pages.CDP:
+		if browser.name() != chromeBrowser {
+			return nil, nil, fmt.Errorf("browser '%s' is not supported by the cdp backend", browser.Name)
+		}
+		if config.NetworkLog {
+			log.Warn("Network log is supported by the selenium backend only")
+		}
+		args := browser.chromeArgs()
+		if browser.Proxy != "" {
+			args = append(args, "--proxy-server="+browser.Proxy)
+			if len(browser.NoProxy) > 0 {
+				args = append(args, "--proxy-bypass-list="+strings.Join(browser.NoProxy, ";"))
+			}
+		}
+		driver, err := pages.NewCDPDriver(browser.Path, args, browser.DownloadDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		return driver, nil, nil
+	case pages.Selenium, "":
+	default:
+		return nil, nil, fmt.Errorf("unknown browser backend: '%s'", config.BrowserBackend)
+	}
+
+	caps, err := browser.capabilities(config.NetworkLog)
+	if err != nil {
+		return nil, nil, err
+	}
+	// connect to selenium server
+	driver, err := pages.NewSeleniumDriver(caps, config.HubURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	driver.W3CActions = browser.name() == firefoxBrowser
+	var network *pages.NetworkSource
+	if config.NetworkLog && browser.name() == chromeBrowser {
+		network = pages.NewNetworkSource(driver.WebDriver, config.HubURL)
+	}
+	return driver, network, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"trading/migrations"
+	"trading/repository"
+)
+
+// runMigrate runs the migrate subcommand: migrate [up | down [steps] | status]
+func runMigrate(db *repository.DB, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		count, err := migrations.Up(db)
+		fmt.Printf("Applied %d migrations\n", count)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("wrong number of steps: '%s'", args[1])
+			}
+			steps = n
+		}
+		count, err := migrations.Down(db, steps)
+		fmt.Printf("Reverted %d migrations\n", count)
+		return err
+	case "status":
+		statuses, err := migrations.List(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(os.Stdout, "%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command: '%s', use up, down [steps] or status", command)
+}
@@ -0,0 +1,208 @@
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"trading/repository"
+)
+
+// files keeps the migrations of every driver in its own directory
+//
+//go:embed sql/*/*.sql
+var files embed.FS
+
+// fileRe matches the names of the migration files, e.g. 0001_create_items.up.sql
+var fileRe = regexp.MustCompile(`\A(\d+)_(\w+)\.(up|down)\.sql\z`)
+
+// Migration is a versioned change of the schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is the state of a migration in the database
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Load returns the embedded migrations of the driver sorted by their versions
+func Load(driver string) ([]*Migration, error) {
+	dir := path.Join("sql", driver)
+	entries, err := files.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration, 0)
+	for _, entry := range entries {
+		match := fileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("wrong name of the migration file: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := files.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", m.Version)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies all pending migrations and returns how many of them are applied
+func Up(db *repository.DB) (int, error) {
+	migrations, applied, err := prepare(db)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		log.Infof("Applying migration %04d_%s", m.Version, m.Name)
+		record := db.Rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)")
+		err = run(db.DB, m.Up, record, m.Version, m.Name, time.Now().UTC())
+		if err != nil {
+			return count, fmt.Errorf("migration %04d_%s: %s", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down reverts the given number of the latest applied migrations
+func Down(db *repository.DB, steps int) (int, error) {
+	migrations, applied, err := prepare(db)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if !applied[m.Version] {
+			continue
+		}
+		if m.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s can't be reverted", m.Version, m.Name)
+		}
+		log.Infof("Reverting migration %04d_%s", m.Version, m.Name)
+		err = run(db.DB, m.Down, db.Rebind("DELETE FROM schema_migrations WHERE version = ?"), m.Version)
+		if err != nil {
+			return count, fmt.Errorf("migration %04d_%s: %s", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// List returns all migrations with their state
+func List(db *repository.DB) ([]Status, error) {
+	migrations, applied, err := prepare(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		statuses = append(statuses, Status{Migration: *m, Applied: applied[m.Version]})
+	}
+	return statuses, nil
+}
+
+// prepare creates the table of the applied migrations and reads it
+func prepare(db *repository.DB) ([]*Migration, map[int]bool, error) {
+	migrations, err := Load(db.Driver())
+	if err != nil {
+		return nil, nil, err
+	}
+	// the table is created with the syntax which all drivers understand
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version int NOT NULL PRIMARY KEY, " +
+		"name varchar(100) NOT NULL, " +
+		"applied_at timestamp NOT NULL" +
+		")")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]bool, 0)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, nil, err
+		}
+		applied[version] = true
+	}
+	return migrations, applied, rows.Err()
+}
+
+// run executes the statements of a migration and records it.
+// MySQL commits DDL statements implicitly, so a failed migration has to be
+// fixed by hand before it's run again there.
+func run(db *sql.DB, script string, record string, args ...interface{}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range statements(script) {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// statements splits a script by the semicolons at the ends of the lines
+func statements(script string) []string {
+	result := make([]string, 0)
+	for _, stmt := range strings.Split(script, ";\n") {
+		stmt = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(stmt), ";"))
+		if stmt != "" {
+			result = append(result, stmt)
+		}
+	}
+	return result
+}
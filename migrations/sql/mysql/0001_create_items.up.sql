@@ -1,5 +1,4 @@
-DROP TABLE IF EXISTS `items`;
-CREATE TABLE `items` (
+CREATE TABLE IF NOT EXISTS `items` (
   `item_id` int(11) NOT NULL AUTO_INCREMENT,
   `instrument` varchar(50) NOT NULL,
   `item_key` varchar(50) NOT NULL,
@@ -7,4 +6,4 @@ CREATE TABLE `items` (
   `qty` int NOT NULL,
   `price` decimal(12,4) DEFAULT NULL,
   PRIMARY KEY (`item_id`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
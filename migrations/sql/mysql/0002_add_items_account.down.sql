@@ -0,0 +1,3 @@
+ALTER TABLE `items`
+  DROP KEY `items_account`,
+  DROP COLUMN `account`;
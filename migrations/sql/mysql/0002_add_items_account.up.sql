@@ -0,0 +1,3 @@
+ALTER TABLE `items`
+  ADD COLUMN `account` varchar(50) NOT NULL DEFAULT 'default' AFTER `item_id`,
+  ADD KEY `items_account` (`account`);
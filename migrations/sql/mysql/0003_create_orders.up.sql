@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `orders` (
+  `order_id` int(11) NOT NULL AUTO_INCREMENT,
+  `account` varchar(50) NOT NULL DEFAULT 'default',
+  `instrument` varchar(50) NOT NULL,
+  `order_key` varchar(50) NOT NULL,
+  `direction` varchar(10) NOT NULL,
+  `type` varchar(10) DEFAULT NULL,
+  `qty` int NOT NULL,
+  `price` decimal(12,4) DEFAULT NULL,
+  PRIMARY KEY (`order_id`),
+  KEY `orders_account` (`account`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,7 @@
+ALTER TABLE `items`
+  DROP KEY `items_account`,
+  ADD KEY `items_account` (`account`),
+  DROP COLUMN `result`,
+  DROP COLUMN `close_price`,
+  DROP COLUMN `closed_at`,
+  DROP COLUMN `status`;
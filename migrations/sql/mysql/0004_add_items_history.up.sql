@@ -0,0 +1,7 @@
+ALTER TABLE `items`
+  ADD COLUMN `status` varchar(10) NOT NULL DEFAULT 'open',
+  ADD COLUMN `closed_at` datetime DEFAULT NULL,
+  ADD COLUMN `close_price` decimal(12,4) DEFAULT NULL,
+  ADD COLUMN `result` decimal(12,4) DEFAULT NULL,
+  DROP KEY `items_account`,
+  ADD KEY `items_account` (`account`, `status`);
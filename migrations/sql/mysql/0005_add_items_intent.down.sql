@@ -0,0 +1,4 @@
+ALTER TABLE `items`
+  DROP KEY `items_intent`,
+  DROP COLUMN `intent_error`,
+  DROP COLUMN `intent`;
@@ -0,0 +1,4 @@
+ALTER TABLE `items`
+  ADD COLUMN `intent` varchar(10) NOT NULL DEFAULT 'confirmed',
+  ADD COLUMN `intent_error` varchar(255) DEFAULT NULL,
+  ADD KEY `items_intent` (`account`, `intent`);
@@ -0,0 +1,3 @@
+ALTER TABLE `items`
+  ADD COLUMN `close_reason` varchar(10) DEFAULT NULL;
+UPDATE `items` SET `close_reason` = 'api' WHERE `status` = 'closed';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS items_account;
+ALTER TABLE items DROP COLUMN account;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS orders (
+  order_id serial PRIMARY KEY,
+  account varchar(50) NOT NULL DEFAULT 'default',
+  instrument varchar(50) NOT NULL,
+  order_key varchar(50) NOT NULL,
+  direction varchar(10) NOT NULL,
+  type varchar(10) DEFAULT NULL,
+  qty int NOT NULL,
+  price decimal(12,4) DEFAULT NULL
+);
+CREATE INDEX IF NOT EXISTS orders_account ON orders (account);
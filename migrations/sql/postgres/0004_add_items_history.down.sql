@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS items_account;
+CREATE INDEX items_account ON items (account);
+ALTER TABLE items
+  DROP COLUMN result,
+  DROP COLUMN close_price,
+  DROP COLUMN closed_at,
+  DROP COLUMN status;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS items_intent;
+ALTER TABLE items
+  DROP COLUMN intent_error,
+  DROP COLUMN intent;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS items (
+  item_id integer PRIMARY KEY AUTOINCREMENT,
+  instrument varchar(50) NOT NULL,
+  item_key varchar(50) NOT NULL,
+  direction varchar(10) NOT NULL,
+  qty int NOT NULL,
+  price decimal(12,4) DEFAULT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE items ADD COLUMN account varchar(50) NOT NULL DEFAULT 'default';
+CREATE INDEX items_account ON items (account);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS items_account;
+CREATE INDEX items_account ON items (account);
+ALTER TABLE items DROP COLUMN result;
+ALTER TABLE items DROP COLUMN close_price;
+ALTER TABLE items DROP COLUMN closed_at;
+ALTER TABLE items DROP COLUMN status;
@@ -0,0 +1,6 @@
+ALTER TABLE items ADD COLUMN status varchar(10) NOT NULL DEFAULT 'open';
+ALTER TABLE items ADD COLUMN closed_at datetime DEFAULT NULL;
+ALTER TABLE items ADD COLUMN close_price decimal(12,4) DEFAULT NULL;
+ALTER TABLE items ADD COLUMN result decimal(12,4) DEFAULT NULL;
+DROP INDEX IF EXISTS items_account;
+CREATE INDEX items_account ON items (account, status);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS items_intent;
+ALTER TABLE items DROP COLUMN intent_error;
+ALTER TABLE items DROP COLUMN intent;
@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN intent varchar(10) NOT NULL DEFAULT 'confirmed';
+ALTER TABLE items ADD COLUMN intent_error varchar(255) DEFAULT NULL;
+CREATE INDEX items_intent ON items (account, intent);
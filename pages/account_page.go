@@ -1,23 +1,29 @@
 package pages
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/tebeka/selenium"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
 // AccountPage represents an account page
 type AccountPage struct {
 	Page Page
+	// Network reads the positions of the JSON of the web app instead of the table, it's optional
+	Network *NetworkSource
+	// networkVerified is set once the ids of the network positions have matched the row ids
+	networkVerified bool
 }
 
+// networkMaxAge is the age of the network positions after which the table is read,
+// the web app pushes the prices of the opened positions much more often
+const networkMaxAge = time.Minute
+
 const (
 	// POSITIONS const
 	POSITIONS = "positions"
@@ -39,6 +45,9 @@ type DbItem struct {
 	Dir        string
 	Qty        int
 	Price      float64
+	// ClosePrice and Result are set when the position is closed
+	ClosePrice float64
+	Result     float64
 }
 
 // Limit struct
@@ -70,6 +79,24 @@ type Position struct {
 	Result       float64 `json:"result"`
 }
 
+// LimitsPayload changes the limits of an opened position,
+// a nil limit is left as it is and a limit with IsUse false is removed
+type LimitsPayload struct {
+	TakeProfit   *Limit `json:"take_profit"`
+	StopLoss     *Limit `json:"stop_loss"`
+	TrailingStop *Limit `json:"trailing_stop"`
+}
+
+// ClosePayload closes a part of an opened position, only one of the fields is set
+type ClosePayload struct {
+	// Quantity is the absolute quantity to close
+	Quantity int `json:"quantity"`
+	// Percent is the part of the current quantity to close
+	Percent float64 `json:"percent"`
+	// TargetQuantity is the quantity which has to remain opened
+	TargetQuantity *int `json:"target_quantity"`
+}
+
 // PositionPayload is for position editing
 type PositionPayload struct {
 	Direction string  `json:"direction"`
@@ -81,7 +108,7 @@ type PositionPayload struct {
 // Order on the Stock Exchange
 type Order struct {
 	BasePosition
-	Type string
+	Type string `json:"type"`
 }
 
 // Item represents common data
@@ -92,6 +119,7 @@ type Item struct {
 	Price      float64
 	Direction  string
 	IsOrder    bool
+	Type       string
 	Limits     map[string]*Limit
 }
 
@@ -102,37 +130,44 @@ type orderWindow struct {
 	Item     *Item
 }
 
+const (
+	// maxSpinSteps limits the clicks on the spinner arrows
+	maxSpinSteps = 50
+	// limitPrecision is the accepted difference of the limit read back
+	limitPrecision = 1e-6
+	// newRowTimeout limits the wait for the row of an added item
+	newRowTimeout = time.Second * 10
+)
+
 var (
 	qtyRe           = regexp.MustCompile(`\A\d+ d+@\z`)
 	isAllProperties = false
+	// limitContainers maps the limit keys to the css class of their container
+	limitContainers = map[string]string{"tp": "take-profit", "sl": "stop-loss", "ts": "trailing-stop"}
 )
 
 func (p *AccountPage) checkSessionExpired() error {
 	widget := p.Page.FindElementByCSS(domPaths["widget_message"])
 	if widget != nil {
-		we, _ := widget.FindElement(selenium.ByCSSSelector, domPaths["css_text"])
-		text, _ := we.Text()
-		if strings.Contains(text, sessionExpired) {
-			log.Debug("Session is expired")
-			if we, err := widget.FindElement(selenium.ByCSSSelector, domPaths["ok"]); err == nil {
-				we.Click()
-				//time.Sleep(time.Millisecond * 3000)
+		if we, err := widget.FindElement(selenium.ByCSSSelector, domPaths["css_text"]); err == nil && we != nil {
+			text, _ := we.Text()
+			if strings.Contains(text, sessionExpired) {
+				log.Debug("Session is expired")
+				if we, err := widget.FindElement(selenium.ByCSSSelector, domPaths["ok"]); err == nil {
+					we.Click()
+				}
+				return ErrSessionExpired
 			}
 		}
 	}
-	err := p.Page.Driver.WaitWithTimeout(p.Page.NotFound(selenium.ByCSSSelector, domPaths["widget_message"]), time.Second*3)
-	if err != nil {
-		return fmt.Errorf(sessionExpired)
+	// the platform redirects to the login page after a logout
+	if p.Page.FindElementByCSS(domPaths["nav_logo"]) == nil {
+		log.Debug("Account page is not shown, probably logged out")
+		return ErrSessionExpired
 	}
 	return nil
 }
 
-func (p *AccountPage) checkDateSortDescending() error {
-	created := p.Page.FindElementByCSS(domPaths["date_created"])
-	p.checkAttr(created, SortASC)
-	return nil
-}
-
 func (p *AccountPage) checkAttr(we selenium.WebElement, attr string) error {
 	if we != nil {
 		class, _ := we.GetAttribute("class")
@@ -163,17 +198,11 @@ func (p *AccountPage) switchAll() error {
 		return nil
 	}
 
-	wg := &sync.WaitGroup{}
 	for _, ctxItem := range ctxItems {
-		wg.Add(1)
-		go func(ctxItem string, wg *sync.WaitGroup) {
-			defer wg.Done()
-			if menuItem, err := ctxMenu.FindElement(selenium.ByCSSSelector, domPaths[ctxItem]); err == nil && menuItem != nil {
-				p.checkAttr(menuItem, "selected")
-			}
-		}(ctxItem, wg)
+		if menuItem, err := ctxMenu.FindElement(selenium.ByCSSSelector, domPaths[ctxItem]); err == nil && menuItem != nil {
+			p.checkAttr(menuItem, "selected")
+		}
 	}
-	wg.Wait()
 	isAllProperties = true
 
 	return nil
@@ -183,13 +212,16 @@ func (p *AccountPage) findItem(target string, id string) (selenium.WebElement, e
 	itemPath := fmt.Sprintf("#item-%s", id)
 	item := p.Page.FindElementByCSS(itemPath)
 	if item == nil {
-		err := p.switchTab(POSITIONS)
+		err := p.switchTab(target)
 		if err != nil {
 			return nil, err
 		}
-		item := p.Page.FindElementByCSS(itemPath)
+		item = p.Page.FindElementByCSS(itemPath)
 		if item == nil {
-			return nil, fmt.Errorf(fmt.Sprintf(positionNotFound, id))
+			if target == ORDERS {
+				return nil, errOrderNotFound(id)
+			}
+			return nil, errPositionNotFound(id)
 		}
 	}
 	return item, nil
@@ -197,14 +229,17 @@ func (p *AccountPage) findItem(target string, id string) (selenium.WebElement, e
 
 // GetPosition returns an opened position
 func (p *AccountPage) GetPosition(id string) (*Position, error) {
-	p.checkSessionExpired()
+	if err := p.checkSessionExpired(); err != nil {
+		return nil, err
+	}
 	p.switchAll()
 
 	log.Infof(fmt.Sprintf("Get a position: %s", id))
 	wePosition, err := p.findItem(POSITIONS, id)
 	if err != nil {
-		return nil, fmt.Errorf(fmt.Sprintf(positionNotFound, id))
+		return nil, err
 	}
+	result := readResult(wePosition)
 	wePosition.Click()
 	time.Sleep(time.Millisecond * 300)
 
@@ -217,28 +252,56 @@ func (p *AccountPage) GetPosition(id string) (*Position, error) {
 	if err != nil {
 		return nil, err
 	}
+	position.Result = result
 
 	return position, nil
 }
 
+// GetPositions returns opened positions mapped by their guids, they are read
+// from the table without opening the dialogs and the missing ones are skipped
+func (p *AccountPage) GetPositions(ids []string) (map[string]*Position, error) {
+	all, err := p.ListPositions()
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]*Position, len(ids))
+	for _, id := range ids {
+		position, ok := all[id]
+		if !ok {
+			log.Debug(fmt.Sprintf(positionNotFound, id))
+			continue
+		}
+		positions[id] = position
+	}
+	return positions, nil
+}
+
 // DeletePosition deletes an opened position
-func (p *AccountPage) DeletePosition(id string) error {
-	p.checkSessionExpired()
-	err := p.Delete(id, POSITIONS)
-	return err
+func (p *AccountPage) DeletePosition(id string) (*Position, error) {
+	// the price and the result are read before the position disappears
+	position, err := p.GetPosition(id)
+	if err != nil {
+		return nil, err
+	}
+	err = p.Delete(id, POSITIONS)
+	if err != nil {
+		return nil, err
+	}
+	return position, nil
 }
 
 // EditPosition edits an opened position
-func (p *AccountPage) EditPosition(item *DbItem, args interface{}) (*DbItem, error) {
-	p.checkSessionExpired()
-	payload, err := p.initEdit(args)
-	if payload == nil {
+func (p *AccountPage) EditPosition(item *DbItem, payload *PositionPayload) (*DbItem, error) {
+	if err := p.checkSessionExpired(); err != nil {
 		return nil, err
 	}
-	log.Infof(fmt.Sprintf("Edit: %#v", args))
+	if payload == nil {
+		return nil, fmt.Errorf(inputDataErrors)
+	}
+	log.Infof(fmt.Sprintf("Edit: %#v", payload))
 	position, err := p.findItem(POSITIONS, item.GUID)
 	if err != nil {
-		return nil, fmt.Errorf(fmt.Sprintf(positionNotFound, item.GUID))
+		return nil, err
 	}
 	position.Click()
 
@@ -266,9 +329,298 @@ func (p *AccountPage) EditPosition(item *DbItem, args interface{}) (*DbItem, err
 	return item, nil
 }
 
+// EditLimits sets, moves or removes the limits of an opened position
+func (p *AccountPage) EditLimits(id string, payload *LimitsPayload) (*Position, error) {
+	if err := p.checkSessionExpired(); err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return nil, fmt.Errorf(inputDataErrors)
+	}
+	log.Infof(fmt.Sprintf("Edit limits: %s", id))
+	position, err := p.findItem(POSITIONS, id)
+	if err != nil {
+		return nil, err
+	}
+	position.Click()
+	time.Sleep(time.Millisecond * 300)
+
+	dlg := &orderWindow{Page: &p.Page, Item: nil, State: "init"}
+	err = dlg.edit()
+	if err != nil {
+		return nil, err
+	}
+	err = dlg.editLimits(payload)
+	if err != nil {
+		dlg.close()
+		return nil, err
+	}
+	err = dlg.confirm()
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("id", id).Info("edited limits of a position")
+
+	// the values are read back as the platform has rounded them
+	return p.GetPosition(id)
+}
+
+// ClosePosition closes the whole or a part of an opened position,
+// the quantity which remains opened is returned in the item
+func (p *AccountPage) ClosePosition(item *DbItem, payload *ClosePayload) (*DbItem, error) {
+	if err := p.checkSessionExpired(); err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return nil, fmt.Errorf(inputDataErrors)
+	}
+	log.Infof(fmt.Sprintf("Close: %#v", payload))
+	position, err := p.findItem(POSITIONS, item.GUID)
+	if err != nil {
+		return nil, err
+	}
+	position.Click()
+	time.Sleep(time.Millisecond * 300)
+
+	dlg := &orderWindow{Page: &p.Page, Item: nil, State: "init"}
+	err = dlg.edit()
+	if err != nil {
+		return nil, err
+	}
+	// the delta is calculated from the live quantity, the stored one can be outdated
+	current, err := dlg.getQuantity()
+	if err != nil {
+		dlg.close()
+		return nil, err
+	}
+	qty, err := closeQuantity(payload, current)
+	if err != nil {
+		dlg.close()
+		return nil, err
+	}
+	if qty == current {
+		dlg.close()
+		closed, err := p.DeletePosition(item.GUID)
+		if err != nil {
+			return nil, err
+		}
+		item.Qty = 0
+		item.ClosePrice = closed.CurrentPrice
+		item.Result = closed.Result
+		return item, nil
+	}
+
+	err = dlg.reduceQuantity(item.Dir, qty)
+	if err != nil {
+		dlg.close()
+		return nil, err
+	}
+	err = dlg.confirm()
+	if err != nil {
+		return nil, err
+	}
+	item.Qty = current - qty
+	log.WithFields(log.Fields{
+		"id":         item.ID,
+		"instrument": item.Instrument,
+		"closed":     qty,
+		"quantity":   item.Qty,
+	}).Info("closed a part of a position")
+
+	return item, nil
+}
+
+// GetOrder returns a pending order
+func (p *AccountPage) GetOrder(id string) (*Order, error) {
+	if err := p.checkSessionExpired(); err != nil {
+		return nil, err
+	}
+
+	log.Infof(fmt.Sprintf("Get an order: %s", id))
+	weOrder, err := p.findItem(ORDERS, id)
+	if err != nil {
+		return nil, err
+	}
+	return p.readOrder(weOrder), nil
+}
+
+// ListPositions returns all opened positions of the platform mapped by their guids
+func (p *AccountPage) ListPositions() (map[string]*Position, error) {
+	if err := p.checkSessionExpired(); err != nil {
+		return nil, err
+	}
+	positions, ok := p.networkPositions()
+	if !ok {
+		return p.tablePositions()
+	}
+	if p.networkVerified {
+		return positions, nil
+	}
+	// the guids are the item_key of the items, the network ones are used only
+	// after they have been found among the item-<guid> ids of the rows
+	table, err := p.tablePositions()
+	if err != nil {
+		return nil, err
+	}
+	p.verifyNetwork(positions, table)
+	return table, nil
+}
+
+// verifyNetwork compares the guids of the network positions with the table ones,
+// the network source is dropped if none of the rows is found in it
+func (p *AccountPage) verifyNetwork(network, table map[string]*Position) {
+	if len(table) == 0 {
+		return
+	}
+	for guid := range table {
+		if _, ok := network[guid]; ok {
+			p.networkVerified = true
+			return
+		}
+	}
+	log.Error("Network positions have other ids than the table, the table is used")
+	p.Network = nil
+}
+
+// tablePositions scrapes the positions of the table
+func (p *AccountPage) tablePositions() (map[string]*Position, error) {
+	p.switchAll()
+
+	err := p.switchTab(POSITIONS)
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(time.Millisecond * 200)
+
+	rows, err := p.scrapeTable("positions_rows", positionCells)
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]*Position, len(rows))
+	for _, row := range rows {
+		if guid := row.GUID(); guid != "" {
+			positions[guid] = parsePosition(row.Cells)
+		}
+	}
+	if len(positions) == 0 {
+		if err := p.checkEmptyTable("positions_table"); err != nil {
+			return nil, err
+		}
+	}
+	log.Debug(fmt.Sprintf("Found %d positions", len(positions)))
+	return positions, nil
+}
+
+// networkPositions returns the positions of the network source if it has them
+func (p *AccountPage) networkPositions() (map[string]*Position, bool) {
+	if p.Network == nil {
+		return nil, false
+	}
+	if err := p.Network.Poll(); err != nil {
+		log.Warn("Performance log is not read: ", err)
+		return nil, false
+	}
+	positions, updated, ok := p.Network.Positions()
+	if !ok {
+		return nil, false
+	}
+	// nothing has come for a while, the socket might be disconnected
+	if time.Since(updated) > networkMaxAge {
+		log.Debug(fmt.Sprintf("Network positions of %s are outdated", updated.Format(time.RFC3339)))
+		return nil, false
+	}
+	log.Debug(fmt.Sprintf("Found %d positions in the network data of %s", len(positions), updated.Format(time.RFC3339)))
+	return positions, true
+}
+
+// GetOrders returns all pending orders mapped by their guids
+func (p *AccountPage) GetOrders() (map[string]*Order, error) {
+	if err := p.checkSessionExpired(); err != nil {
+		return nil, err
+	}
+
+	err := p.switchTab(ORDERS)
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(time.Millisecond * 200)
+
+	orders := make(map[string]*Order, 0)
+	for _, row := range p.Page.FindElementsByCSS(domPaths["orders_rows"]) {
+		id, _ := row.GetAttribute("id")
+		if !strings.HasPrefix(id, "item-") {
+			continue
+		}
+		guid := strings.Replace(id, "item-", "", 1)
+		orders[guid] = p.readOrder(row)
+	}
+	log.Debug(fmt.Sprintf("Found %d orders", len(orders)))
+	return orders, nil
+}
+
+// readPosition reads the cells of the position row
+func (p *AccountPage) readPosition(row selenium.WebElement) *Position {
+	cells := make(map[string]string, len(positionCells))
+	for _, name := range positionCells {
+		we, err := row.FindElement(selenium.ByCSSSelector, domPaths[name])
+		if err != nil || we == nil {
+			continue
+		}
+		txt, _ := we.Text()
+		cells[name] = strings.TrimSpace(txt)
+	}
+	return parsePosition(cells)
+}
+
+// readResult returns the result of the position row
+func readResult(row selenium.WebElement) float64 {
+	we, err := row.FindElement(selenium.ByCSSSelector, domPaths["result"])
+	if err != nil || we == nil {
+		return 0
+	}
+	txt, _ := we.Text()
+	return parseNumber(txt)
+}
+
+// readOrder reads the cells of a row in the orders table
+func (p *AccountPage) readOrder(row selenium.WebElement) *Order {
+	cell := func(name string) string {
+		we, err := row.FindElement(selenium.ByCSSSelector, domPaths[name])
+		if err != nil || we == nil {
+			return ""
+		}
+		txt, _ := we.Text()
+		return strings.TrimSpace(txt)
+	}
+
+	order := &Order{}
+	order.Instrument = cell("cell_name")
+	order.Direction = strings.ToLower(cell("cell_dir"))
+	order.Type = strings.ToLower(cell("cell_type"))
+	order.TakeProfit = cell("cell_tp")
+	order.StopLoss = cell("cell_sl")
+	order.DateCreated = cell("cell_created")
+
+	str := cleanNumber(cell("cell_qty"))
+	if qty, err := strconv.Atoi(str); err == nil {
+		order.Quantity = qty
+	}
+	str = cleanNumber(cell("cell_price"))
+	if price, err := strconv.ParseFloat(str, 64); err == nil {
+		order.Price = price
+	}
+	str = cleanNumber(cell("cell_curprice"))
+	if price, err := strconv.ParseFloat(str, 64); err == nil {
+		order.CurrentPrice = price
+	}
+	return order
+}
+
 // DeleteOrder deletes an opened order
 func (p *AccountPage) DeleteOrder(id string) error {
-	p.checkSessionExpired()
+	if err := p.checkSessionExpired(); err != nil {
+		return err
+	}
 	err := p.Delete(id, ORDERS)
 	return err
 }
@@ -278,7 +630,7 @@ func (p *AccountPage) Delete(id string, target string) error {
 	itemPath := fmt.Sprintf("#item-%s", id)
 	_, err := p.findItem(target, id)
 	if err != nil {
-		return fmt.Errorf(fmt.Sprintf(positionNotFound, id))
+		return err
 	}
 
 	p.Page.MouseHoverToElement(itemPath)
@@ -295,103 +647,42 @@ func (p *AccountPage) Delete(id string, target string) error {
 	if rm == nil {
 		return fmt.Errorf(fmt.Sprintf(cssError, menuItem))
 	}
-	rm.MoveTo(0, 0)
+	p.Page.Driver.MoveTo(rm)
 	rm.Click()
 	time.Sleep(time.Millisecond * 100)
 	widget := p.Page.FindElementByCSS(domPaths["widget_message"])
-	if okBtn, err := widget.FindElement(selenium.ByCSSSelector, domPaths["ok_btn"]); err == nil {
-		okBtn.Click()
+	if widget != nil {
+		if okBtn, err := widget.FindElement(selenium.ByCSSSelector, domPaths["ok_btn"]); err == nil {
+			okBtn.Click()
+		}
 	}
 	widget = p.Page.FindElementByCSS(domPaths["widget_message"])
 	if widget != nil {
-		if txt, err := widget.FindElement(selenium.ByCSSSelector, domPaths["css_text"]); err == nil {
-			return fmt.Errorf(txt.Text())
-		}
+		title, text := widgetMessage(widget)
+		return rejected(title, text, "")
 	}
 	return nil
 }
 
-func (p *AccountPage) initEdit(args interface{}) (*PositionPayload, error) {
-	in := &PositionPayload{}
-	data, ok := args.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf(fmt.Sprintf(wrongTypeInput, args))
-	}
-	jsonbody, _ := json.Marshal(data["quantity"])
-	err := json.Unmarshal(jsonbody, &in)
-	if err != nil {
-		return nil, fmt.Errorf("error while parsing args")
+// Add adds a new position/order
+func (p *AccountPage) Add(item *Item) (*Item, error) {
+	if err := p.checkSessionExpired(); err != nil {
+		return nil, err
 	}
-	return in, nil
-}
 
-func (p *AccountPage) initAdd(args interface{}) (*Item, error) {
-	in := &Item{}
-	data, ok := args.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf(fmt.Sprintf(wrongTypeInput, args))
-	}
-	instrument, ok := data["instrument"].(string)
-	if !ok {
-		return nil, fmt.Errorf(instrumentNotDefined)
-	}
-	direction, ok := data["direction"].(string)
-	if !ok || direction == "" {
-		return nil, fmt.Errorf(directionNotDefined)
-	}
-	isOrder, ok := data["is_order"].(bool)
-	if !ok {
-		return nil, fmt.Errorf(typeNotDefined)
-	}
-	qty, ok := data["qty"].(float64)
-	if !ok {
-		return nil, fmt.Errorf("qty must be int")
-	}
-	price, ok := data["price"].(float64)
-	if !ok && isOrder {
-		return nil, fmt.Errorf("price not found")
+	if item == nil {
+		return nil, fmt.Errorf(inputDataErrors)
 	}
+	log.Infof(fmt.Sprintf("Add: %#v", item))
 
-	var limits map[string]interface{}
-	if data["limits"] != nil {
-		limits = data["limits"].(map[string]interface{})
-	}
-	tp := Limit{}
-	jsonbody, _ := json.Marshal(limits["tp"])
-	err := json.Unmarshal(jsonbody, &tp)
-	if err != nil {
-		return nil, fmt.Errorf("error while parsing limits")
+	name := POSITIONS
+	if item.IsOrder {
+		name = ORDERS
 	}
-	in.Limits = make(map[string]*Limit, 0)
-	in.Limits["tp"] = &tp
-
-	sl := Limit{}
-	jsonbody, _ = json.Marshal(limits["sl"])
-	err = json.Unmarshal(jsonbody, &sl)
+	// the new row is found by the difference of the rows
+	before, err := p.rowIDs(name)
 	if err != nil {
-		return nil, fmt.Errorf("error while parsing limits")
-	}
-	in.Limits["sl"] = &sl
-
-	in.Instrument = instrument
-	in.Direction = direction
-	in.Qty = int(qty)
-	in.IsOrder = isOrder
-	in.Price = price
-
-	return in, nil
-}
-
-// Add adds a new position/order
-func (p *AccountPage) Add(args interface{}) (*Item, error) {
-	p.checkSessionExpired()
-	p.checkDateSortDescending()
-
-	log.Infof(fmt.Sprintf("Add: %#v", args))
-
-	item, err := p.initAdd(args)
-	if item == nil {
-		return nil, fmt.Errorf(inputDataErrors)
+		return nil, err
 	}
 
 	dlg := &orderWindow{Page: &p.Page, Item: item, State: "init"}
@@ -399,31 +690,27 @@ func (p *AccountPage) Add(args interface{}) (*Item, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = dlg.setDirection(item.Direction)
+	err = dlg.fill(item)
+	if err == nil {
+		err = dlg.confirm()
+	}
 	if err != nil {
+		// the dialog is left open by a failed step
+		dlg.close()
 		return nil, err
 	}
-	// set quantity
-	if item.Qty != 0 {
-		dlg.setQuantity(item.Qty)
-	}
-
-	// set limits
-	if item.Limits != nil {
-		dlg.setLimit(item.Limits)
-	}
-	err = dlg.confirm()
+	id, err := p.waitNewRow(name, before, item)
 	if err != nil {
 		return nil, err
 	}
-	var name string
+	item.Key = id
+
+	// the type of the pending order is defined by the platform
 	if item.IsOrder {
-		name = ORDERS
-	} else {
-		name = POSITIONS
+		if order, err := p.GetOrder(id); err == nil {
+			item.Type = order.Type
+		}
 	}
-	id, err := p.findKey(name)
-	item.Key = id
 
 	log.WithFields(log.Fields{
 		"instrument": item.Instrument,
@@ -433,18 +720,73 @@ func (p *AccountPage) Add(args interface{}) (*Item, error) {
 	return item, nil
 }
 
-// findID finds a real guid id
-func (p *AccountPage) findKey(name string) (string, error) {
-	var guid string
-	p.switchTab(name)
-	rowPath := fmt.Sprintf("%s_last_row", name)
-	lastRow := p.Page.FindElementByCSS(domPaths[rowPath])
-	if lastRow == nil {
-		return guid, fmt.Errorf(positionTableEmpty)
+// rowIDs returns the guids of the rows in the table
+func (p *AccountPage) rowIDs(name string) (map[string]bool, error) {
+	err := p.switchTab(name)
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(time.Millisecond * 200)
+
+	ids := make(map[string]bool, 0)
+	for _, row := range p.Page.FindElementsByCSS(domPaths[name+"_rows"]) {
+		id, _ := row.GetAttribute("id")
+		if strings.HasPrefix(id, "item-") {
+			ids[strings.Replace(id, "item-", "", 1)] = true
+		}
+	}
+	return ids, nil
+}
+
+// waitNewRow waits for the row of the added item, which is not in the rows
+// before. Only the rows with the instrument, the direction and the quantity
+// of the item are taken, several of them is an ambiguity.
+func (p *AccountPage) waitNewRow(name string, before map[string]bool, item *Item) (string, error) {
+	deadline := time.Now().Add(newRowTimeout)
+	for {
+		after, err := p.rowIDs(name)
+		if err != nil {
+			return "", err
+		}
+		added := make([]string, 0)
+		for id := range after {
+			if !before[id] {
+				added = append(added, id)
+			}
+		}
+		matched := make([]string, 0, len(added))
+		for _, id := range added {
+			row := p.Page.FindElementByCSS(fmt.Sprintf("#item-%s", id))
+			if row != nil && p.matchRow(name, row, item) {
+				matched = append(matched, id)
+			}
+		}
+		switch {
+		case len(matched) == 1:
+			log.Debug(fmt.Sprintf("Found a new row %s of %d added", matched[0], len(added)))
+			return matched[0], nil
+		case len(matched) > 1:
+			return "", errAmbiguousKey(item.Instrument, matched)
+		}
+		if time.Now().After(deadline) {
+			return "", errKeyNotFound(item.Instrument, len(added))
+		}
+		time.Sleep(time.Millisecond * 300)
+	}
+}
+
+// matchRow checks the instrument, the direction and the quantity of the row
+func (p *AccountPage) matchRow(name string, row selenium.WebElement, item *Item) bool {
+	var base BasePosition
+	if name == ORDERS {
+		base = p.readOrder(row).BasePosition
+	} else {
+		base = p.readPosition(row).BasePosition
 	}
-	id, _ := lastRow.GetAttribute("id")
-	guid = strings.Replace(id, "item-", "", 1)
-	return guid, nil
+	w := &orderWindow{}
+	return w.checkName(item.Instrument, base.Instrument) &&
+		strings.EqualFold(item.Direction, base.Direction) &&
+		(item.Qty == 0 || item.Qty == base.Quantity)
 }
 
 func (p *AccountPage) switchTab(name string) error {
@@ -473,18 +815,21 @@ func (w *orderWindow) open() error {
 	we.SendKeys(w.Item.Instrument)
 	if w.getResult(0) == nil {
 		w.Page.FindElementByCSS(domPaths["close"]).Click()
-		return fmt.Errorf(instrumentNotDefined)
+		return errInstrumentNotFound(w.Item.Instrument)
 	}
 	result, _, err := w.searchResult(w.Item.Instrument)
 	if err != nil {
 		return err
 	}
 	result.Click()
+	w.State = "open"
 	widgetMsg := w.Page.FindElementByCSS(domPaths["widget_message"])
 	if widgetMsg != nil {
-		w.decode(widgetMsg)
+		if err := w.decode(widgetMsg); err != nil {
+			w.close()
+			return err
+		}
 	}
-	w.State = "open"
 	return nil
 }
 
@@ -504,6 +849,30 @@ func (w *orderWindow) info() error {
 	return nil
 }
 
+// fill sets the direction, the quantity, the price of the pending order and the limits
+func (w *orderWindow) fill(item *Item) error {
+	err := w.setDirection(item.Direction)
+	if err != nil {
+		return err
+	}
+	if item.Qty != 0 {
+		err = w.setQuantity(item.Qty)
+		if err != nil {
+			return err
+		}
+	}
+	if item.IsOrder {
+		err = w.setOrderPrice(item.Price)
+		if err != nil {
+			return err
+		}
+	}
+	if item.Limits != nil {
+		return w.setLimit(item.Limits)
+	}
+	return nil
+}
+
 func (w *orderWindow) close() error {
 	err := w.checkOpen()
 	if err != nil {
@@ -527,17 +896,21 @@ func (w *orderWindow) confirm() error {
 	confirmBtn.Click()
 	time.Sleep(time.Millisecond * 1000)
 
-	var txt string
+	var title, txt string
 	widget := w.Page.FindElementByCSS(domPaths["widget_message"])
 	if widget != nil {
-		we, _ := widget.FindElement(selenium.ByCSSSelector, domPaths["css_text"])
-		txt, _ = we.Text()
+		title, txt = widgetMessage(widget)
 	}
 	confirmBtn = w.Page.FindElementByCSS(domPaths["confirm_btn"])
 
 	if confirmBtn != nil {
-		log.Error(txt)
-		return fmt.Errorf(txt)
+		instrument := ""
+		if w.Item != nil {
+			instrument = w.Item.Instrument
+		}
+		err := rejected(title, txt, instrument)
+		log.WithField("code", err.Code).Error(err.Error())
+		return err
 	}
 	log.Info("Operation is confirmed")
 	w.State = "confirmed"
@@ -549,32 +922,39 @@ func (w *orderWindow) getResearchName(result selenium.WebElement) (string, error
 		return "", nil
 	}
 	we, err := result.FindElement(selenium.ByCSSSelector, domPaths["instrument_name"])
-	txt, _ := we.Text()
-	if err != nil {
-		log.Errorf(instrumentNotFound)
-		return "", fmt.Errorf(instrumentNotFound)
+	if err != nil || we == nil {
+		log.Errorf(instrumentNotFound, w.Item.Instrument)
+		return "", errInstrumentNotFound(w.Item.Instrument)
 	}
+	txt, _ := we.Text()
 	return txt, nil
 }
 
+// widgetMessage returns the title and the text of a pop-up
+func widgetMessage(we selenium.WebElement) (string, string) {
+	var title, text string
+	if titleWebElem, err := we.FindElement(selenium.ByCSSSelector, domPaths["css_title"]); err == nil && titleWebElem != nil {
+		title, _ = titleWebElem.Text()
+	}
+	if textWebElem, err := we.FindElement(selenium.ByCSSSelector, domPaths["css_text"]); err == nil && textWebElem != nil {
+		text, _ = textWebElem.Text()
+	}
+	return strings.TrimSpace(title), strings.TrimSpace(text)
+}
+
 // decode text pop-up
 func (w *orderWindow) decode(we selenium.WebElement) error {
-	titleWebElem, _ := we.FindElement(selenium.ByCSSSelector, domPaths["css_title"])
-	title, _ := titleWebElem.Text()
-	textWebElem, _ := we.FindElement(selenium.ByCSSSelector, domPaths["css_text"])
-	text, _ := textWebElem.Text()
-
-	if title == "Insufficient Funds" {
+	title, text := widgetMessage(we)
+	err := decodeMessage(title, text, w.Item.Instrument)
+	if err == nil {
+		log.Debug("decoded message")
+		return nil
+	}
+	if err == ErrInsufficientFunds {
 		w.Insfunds = true
-	} else if title == "Maximum Quantity Limit" {
-		qty, _ := strconv.Atoi(text)
-		return fmt.Errorf(fmt.Sprintf(maxQtyLimit, qty))
-	} else if title == "Minimum Quantity Limit" {
-		qty, _ := strconv.Atoi(text)
-		return fmt.Errorf(fmt.Sprintf(minQtyLimit, qty))
-	}
-	log.Debug("decoded message")
-	return nil
+	}
+	log.WithField("code", err.Code).Warn(err.Error())
+	return err
 }
 
 func (w *orderWindow) checkName(what, where string) bool {
@@ -599,8 +979,7 @@ func (w *orderWindow) searchResult(product string) (selenium.WebElement, string,
 			}
 			if name == "" {
 				w.Page.FindElementByCSS(domPaths["close"]).Click()
-				msg := fmt.Sprintf(instrumentNotFound, product)
-				return nil, "", errors.New(msg)
+				return nil, "", errInstrumentNotFound(product)
 			}
 			log.Debug(name)
 
@@ -807,6 +1186,49 @@ func (w *orderWindow) editQuantity(params *PositionPayload) (int, error) {
 	return qty, nil
 }
 
+// reduceQuantity trades the quantity in the opposite direction of the position
+func (w *orderWindow) reduceQuantity(direction string, qty int) error {
+	err := w.checkOpen()
+	if err != nil {
+		return err
+	}
+	opposite := SELL
+	switch direction {
+	case BUY:
+	case SELL:
+		opposite = BUY
+	default:
+		return fmt.Errorf(directionNotDefined)
+	}
+	marketOrderTab := w.Page.FindElementByCSS(domPaths["market_order_tab"])
+	if marketOrderTab == nil {
+		return fmt.Errorf(cssError, domPaths["market_order_tab"])
+	}
+	marketOrderTab.Click()
+	err = w.setDirection(opposite)
+	if err != nil {
+		return err
+	}
+	return w.setQuantity(qty)
+}
+
+// closeQuantity returns the quantity to close of the current one
+func closeQuantity(payload *ClosePayload, current int) (int, error) {
+	var qty int
+	switch {
+	case payload.TargetQuantity != nil:
+		qty = current - *payload.TargetQuantity
+	case payload.Percent != 0:
+		qty = int(math.Round(float64(current) * payload.Percent / 100))
+	default:
+		qty = payload.Quantity
+	}
+	if qty <= 0 || qty > current {
+		return 0, &BrokerError{Code: CodeInvalidQuantity, Message: fmt.Sprintf(closeQtyInvalid, qty, current)}
+	}
+	return qty, nil
+}
+
 // Set quantity
 func (w *orderWindow) setQuantity(qty int) error {
 	err := w.checkOpen()
@@ -831,43 +1253,217 @@ func (w *orderWindow) setQuantity(qty int) error {
 	return nil
 }
 
+// Set a price of the pending order
+func (w *orderWindow) setOrderPrice(price float64) error {
+	err := w.checkOpen()
+	if err != nil {
+		return err
+	}
+	if price <= 0 {
+		return fmt.Errorf(unacceptableValue, strconv.FormatFloat(price, 'f', -1, 64))
+	}
+	tab := w.Page.FindElementByCSS(domPaths["limit_stop_tab"])
+	if tab == nil {
+		return fmt.Errorf(cssError, domPaths["limit_stop_tab"])
+	}
+	tab.Click()
+	time.Sleep(time.Millisecond * 100)
+
+	we := w.Page.FindElementByCSS(domPaths["ls_price_input"])
+	if we == nil {
+		return fmt.Errorf(cssError, domPaths["ls_price_input"])
+	}
+	we.Clear()
+	we.SendKeys(strconv.FormatFloat(price, 'f', -1, 64))
+
+	log.Debug(fmt.Sprintf("Add. order price set: %v", price))
+	return nil
+}
+
 // set limit in order window
 func (w *orderWindow) setLimit(limits map[string]*Limit) error {
 	err := w.checkOpen()
 	if err != nil {
 		return err
 	}
-	tpUse := limits["tp"].IsUse
-	slUse := limits["sl"].IsUse
-	if !w.Item.IsOrder {
-		if tpUse {
-			w.buttonToggle("market_tp_toggle")
+	prefix, dialog := "market", "market-order"
+	if w.Item.IsOrder {
+		prefix, dialog = "ls", "limit_stop"
+	}
+	for _, key := range []string{"tp", "sl"} {
+		limit := limits[key]
+		if limit == nil {
+			continue
 		}
-		if slUse {
-			w.buttonToggle("market_sl_toggle")
+		err = w.switchLimit(dialog, key, limit.IsUse)
+		if err != nil {
+			return err
 		}
-	} else {
-		if tpUse {
-			w.buttonToggle("ls_tp_toggle")
+		if !limit.IsUse {
+			continue
 		}
-		if slUse {
-			w.buttonToggle("ls_sl_toggle")
+		err = w.setLimitValue(prefix, dialog, key, limit)
+		if err != nil {
+			return err
 		}
 	}
 
-	log.WithFields(log.Fields{
-		"tp.Use":      limits["tp"].IsUse,
-		"tp.Distance": limits["tp"].Distance,
-		"tp.Price":    limits["tp"].Price,
-		"tp.Result":   limits["tp"].Result,
-		"sl.Use":      limits["sl"].IsUse,
-		"sl.Distance": limits["sl"].Distance,
-		"sl.Price":    limits["sl"].Price,
-		"sl.Result":   limits["sl"].Result,
-	}).Info("set limit")
+	fields := log.Fields{}
+	for key, limit := range limits {
+		if limit != nil && limit.IsUse {
+			fields[key] = fmt.Sprintf("%#v", *limit)
+		}
+	}
+	log.WithFields(fields).Info("set limit")
+	return nil
+}
+
+// editLimits switches the limits of the position dialog and sets their values
+func (w *orderWindow) editLimits(payload *LimitsPayload) error {
+	err := w.checkOpen()
+	if err != nil {
+		return err
+	}
+	tab := w.Page.FindElementByCSS(domPaths["limits_tab"])
+	if tab == nil {
+		return fmt.Errorf(cssError, domPaths["limits_tab"])
+	}
+	tab.Click()
+	time.Sleep(time.Millisecond * 100)
+
+	limits := []struct {
+		key   string
+		limit *Limit
+	}{
+		{"tp", payload.TakeProfit},
+		{"sl", payload.StopLoss},
+		{"ts", payload.TrailingStop},
+	}
+	for _, l := range limits {
+		if l.limit == nil {
+			continue
+		}
+		err = w.switchLimit("position", l.key, l.limit.IsUse)
+		if err != nil {
+			return err
+		}
+		if !l.limit.IsUse {
+			log.Debugf("Edit. %s removed", l.key)
+			continue
+		}
+		err = w.setLimitValue("position", "position", l.key, l.limit)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// switchLimit turns the limit on or off unless it's already in that state
+func (w *orderWindow) switchLimit(dialog, key string, on bool) error {
+	container := limitContainers[key]
+	togglePath := fmt.Sprintf(domPaths["limit_toggle"], dialog, container, container)
+	toggle := w.Page.FindElementByCSS(togglePath)
+	if toggle == nil {
+		return fmt.Errorf(cssError, togglePath)
+	}
+	class, _ := toggle.GetAttribute("class")
+	if strings.Contains(class, "active") != on {
+		toggle.Click()
+		time.Sleep(time.Millisecond * 100)
+	}
+	return nil
+}
+
+// limitValue returns the field of the limit which is set and its value
+func limitValue(limit *Limit) (string, float64) {
+	switch {
+	case limit.Price != 0:
+		return "price", limit.Price
+	case limit.Distance != 0:
+		return "distance", limit.Distance
+	case limit.Result != 0:
+		return "result", limit.Result
+	}
+	return "", 0
+}
+
+// setLimitValue types the value of the limit and reads it back
+func (w *orderWindow) setLimitValue(prefix, dialog, key string, limit *Limit) error {
+	field, value := limitValue(limit)
+	if field == "" {
+		return fmt.Errorf(limitNotDefined, key)
+	}
+	boxPath := fmt.Sprintf(domPaths["limit_box"], dialog, limitContainers[key])
+	box := w.Page.FindElementByCSS(boxPath)
+	if box == nil {
+		return fmt.Errorf(cssError, boxPath)
+	}
+	if tab, err := box.FindElement(selenium.ByCSSSelector, fmt.Sprintf(domPaths["limit_tab"], field)); err == nil {
+		tab.Click()
+		time.Sleep(time.Millisecond * 100)
+	}
+	inputPath := fmt.Sprintf(domPaths["limit_input"], field)
+	input, err := box.FindElement(selenium.ByCSSSelector, inputPath)
+	if err != nil {
+		return fmt.Errorf(cssError, inputPath)
+	}
+	input.Clear()
+	input.SendKeys(formatFloat(value))
+	time.Sleep(time.Millisecond * 100)
+
+	// the platform rounds the distance to its step, the spinners move it
+	// to the requested value
+	if field == "distance" {
+		w.spinDistance(prefix, key, input, value)
+	}
+
+	actual, err := readInputValue(input)
+	if err != nil {
+		return err
+	}
+	if math.Abs(actual-value) > limitPrecision {
+		return fmt.Errorf(limitNotSet, key, field, formatFloat(actual), formatFloat(value))
+	}
+	log.Debugf("Add. %s %s set: %s", key, field, formatFloat(actual))
+	return nil
+}
+
+// spinDistance clicks the spinner arrows until the distance reaches the value
+func (w *orderWindow) spinDistance(prefix, key string, input selenium.WebElement, value float64) {
+	last := ""
+	for i := 0; i < maxSpinSteps; i++ {
+		current, err := readInputValue(input)
+		if err != nil || math.Abs(current-value) <= limitPrecision {
+			return
+		}
+		arrow := "up"
+		if current > value {
+			arrow = "down"
+		}
+		// the value is between two steps of the spinner
+		if last != "" && last != arrow {
+			return
+		}
+		w.buttonToggle(fmt.Sprintf("%s_%s_%s", prefix, key, arrow))
+		time.Sleep(time.Millisecond * 50)
+		last = arrow
+	}
+}
+
+// readInputValue parses the number in the input field
+func readInputValue(input selenium.WebElement) (float64, error) {
+	str, err := input.GetAttribute("value")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(cleanNumber(str), 64)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
 func (w *orderWindow) buttonToggle(btnPath string) {
 	toggle := w.Page.FindElementByCSS(domPaths[btnPath])
 	if toggle != nil {
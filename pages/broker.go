@@ -0,0 +1,34 @@
+package pages
+
+// Broker executes trading operations on behalf of the API
+type Broker interface {
+	// Add opens a new position or places a pending order
+	Add(item *Item) (*Item, error)
+	// GetPosition returns an opened position by its guid
+	GetPosition(id string) (*Position, error)
+	// GetPositions returns opened positions mapped by their guids, the ids
+	// which aren't found are missing in the map
+	GetPositions(ids []string) (map[string]*Position, error)
+	// ListPositions returns all opened positions of the platform mapped by their guids
+	ListPositions() (map[string]*Position, error)
+	// EditPosition changes the quantity of an opened position
+	EditPosition(item *DbItem, payload *PositionPayload) (*DbItem, error)
+	// EditLimits sets, moves or removes take-profit, stop-loss and trailing stop
+	EditLimits(id string, payload *LimitsPayload) (*Position, error)
+	// ClosePosition closes the whole or a part of an opened position
+	ClosePosition(item *DbItem, payload *ClosePayload) (*DbItem, error)
+	// DeletePosition closes an opened position and returns its last state
+	DeletePosition(id string) (*Position, error)
+	// GetOrder returns a pending order by its guid
+	GetOrder(id string) (*Order, error)
+	// GetOrders returns all pending orders mapped by their guids
+	GetOrders() (map[string]*Order, error)
+	// DeleteOrder cancels a pending order
+	DeleteOrder(id string) error
+}
+
+var (
+	_ Broker = (*AccountPage)(nil)
+	_ Broker = (*MemoryBroker)(nil)
+	_ Broker = (*SerialBroker)(nil)
+)
@@ -0,0 +1,606 @@
+package pages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/tebeka/selenium"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// findScript finds the elements under the node by a selenium locator
+const findScript = `function(by, value, all) {
+	var root = this, doc = root.ownerDocument || root, found = [];
+	var byCSS = function(selector) { return Array.prototype.slice.call(root.querySelectorAll(selector)); };
+	switch (by) {
+	case "css selector": found = byCSS(value); break;
+	case "id": found = byCSS("#" + CSS.escape(value)); break;
+	case "name": found = byCSS("[name=\"" + CSS.escape(value) + "\"]"); break;
+	case "class name": found = byCSS("." + CSS.escape(value)); break;
+	case "tag name": found = byCSS(value); break;
+	case "link text":
+	case "partial link text":
+		found = byCSS("a").filter(function(a) {
+			var text = a.innerText.trim();
+			return by == "link text" ? text == value : text.indexOf(value) >= 0;
+		});
+		break;
+	case "xpath":
+		var result = doc.evaluate(value, root, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null);
+		for (var i = 0; i < result.snapshotLength; i++) {
+			found.push(result.snapshotItem(i));
+		}
+		break;
+	default:
+		throw new Error("unknown locator: " + by);
+	}
+	return all ? found : (found[0] || null);
+}`
+
+// cdpObjectGroup is the group of the remote objects of the elements, it's released
+// by the executor after every operation
+const cdpObjectGroup = "elements"
+
+var _ Driver = (*CDPDriver)(nil)
+
+// CDPDriver drives a local Chromium over the DevTools protocol
+type CDPDriver struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	allocCancel context.CancelFunc
+
+	mu          sync.Mutex
+	loadTimeout time.Duration
+	// the mouse is moved by MoveTo of the elements and clicked by Click
+	mouseX, mouseY float64
+}
+
+// NewCDPDriver starts a headless Chromium, path is found if it's empty and
+// the args are the switches of the command line as they are given to chrome.
+// The files are downloaded to the download dir if it's set.
+func NewCDPDriver(path string, args []string, downloadDir string) (*CDPDriver, error) {
+	opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	if path != "" {
+		opts = append(opts, chromedp.ExecPath(path))
+	}
+	for _, arg := range args {
+		name, value := parseSwitch(arg)
+		opts = append(opts, chromedp.Flag(name, value))
+	}
+
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	// the first run starts the browser
+	actions := make([]chromedp.Action, 0)
+	if downloadDir != "" {
+		actions = append(actions, page.SetDownloadBehavior(page.SetDownloadBehaviorBehaviorAllow).WithDownloadPath(downloadDir))
+	}
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		cancel()
+		allocCancel()
+		return nil, err
+	}
+	return &CDPDriver{ctx: ctx, cancel: cancel, allocCancel: allocCancel, loadTimeout: time.Second * 10}, nil
+}
+
+// parseSwitch splits a switch like --window-size=800,600 into its name and value
+func parseSwitch(arg string) (string, interface{}) {
+	arg = strings.TrimLeft(arg, "-")
+	if i := strings.Index(arg, "="); i >= 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, true
+}
+
+func (d *CDPDriver) run(actions ...chromedp.Action) error {
+	return chromedp.Run(d.ctx, actions...)
+}
+
+// Get opens the url and waits for the page load
+func (d *CDPDriver) Get(url string) error {
+	d.mu.Lock()
+	timeout := d.loadTimeout
+	d.mu.Unlock()
+	ctx, cancel := context.WithTimeout(d.ctx, timeout)
+	defer cancel()
+	return chromedp.Run(ctx, chromedp.Navigate(url))
+}
+
+// Title returns the title of the page
+func (d *CDPDriver) Title() (string, error) {
+	var title string
+	err := d.run(chromedp.Title(&title))
+	return title, err
+}
+
+// document returns the remote object of the document
+func (d *CDPDriver) document() (runtime.RemoteObjectID, error) {
+	var obj *runtime.RemoteObject
+	err := d.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		var exc *runtime.ExceptionDetails
+		var err error
+		obj, exc, err = runtime.Evaluate("document").WithObjectGroup(cdpObjectGroup).Do(ctx)
+		if err == nil && exc != nil {
+			err = exc
+		}
+		return err
+	}))
+	if err != nil {
+		return "", err
+	}
+	return obj.ObjectID, nil
+}
+
+// FindElement finds the first element in the document
+func (d *CDPDriver) FindElement(by, value string) (selenium.WebElement, error) {
+	doc, err := d.document()
+	if err != nil {
+		return nil, err
+	}
+	return d.findElement(doc, by, value)
+}
+
+// FindElements finds all elements in the document
+func (d *CDPDriver) FindElements(by, value string) ([]selenium.WebElement, error) {
+	doc, err := d.document()
+	if err != nil {
+		return nil, err
+	}
+	return d.findElements(doc, by, value)
+}
+
+func (d *CDPDriver) findElement(root runtime.RemoteObjectID, by, value string) (selenium.WebElement, error) {
+	obj, err := d.call(root, findScript, false, by, value, false)
+	if err != nil {
+		return nil, err
+	}
+	if obj.ObjectID == "" {
+		return nil, fmt.Errorf(elementNotFound, by, value)
+	}
+	return &cdpElement{driver: d, id: obj.ObjectID}, nil
+}
+
+func (d *CDPDriver) findElements(root runtime.RemoteObjectID, by, value string) ([]selenium.WebElement, error) {
+	list, err := d.call(root, findScript, false, by, value, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var props []*runtime.PropertyDescriptor
+	err = d.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		var exc *runtime.ExceptionDetails
+		props, _, _, exc, err = runtime.GetProperties(list.ObjectID).WithOwnProperties(true).Do(ctx)
+		if err == nil && exc != nil {
+			err = exc
+		}
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	// the properties of the array are its indexes and its length
+	indexes := make(map[int]runtime.RemoteObjectID, len(props))
+	for _, prop := range props {
+		index, err := strconv.Atoi(prop.Name)
+		if err != nil || prop.Value == nil || prop.Value.ObjectID == "" {
+			continue
+		}
+		indexes[index] = prop.Value.ObjectID
+	}
+	keys := make([]int, 0, len(indexes))
+	for index := range indexes {
+		keys = append(keys, index)
+	}
+	sort.Ints(keys)
+
+	elements := make([]selenium.WebElement, 0, len(keys))
+	for _, index := range keys {
+		elements = append(elements, &cdpElement{driver: d, id: indexes[index]})
+	}
+	return elements, nil
+}
+
+// call calls the function with the remote object as this, the arguments are
+// passed by their JSON
+func (d *CDPDriver) call(id runtime.RemoteObjectID, fn string, byValue bool, args ...interface{}) (*runtime.RemoteObject, error) {
+	arguments := make([]*runtime.CallArgument, 0, len(args))
+	for _, arg := range args {
+		data, err := json.Marshal(arg)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, &runtime.CallArgument{Value: data})
+	}
+
+	var obj *runtime.RemoteObject
+	err := d.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		var exc *runtime.ExceptionDetails
+		var err error
+		obj, exc, err = runtime.CallFunctionOn(fn).
+			WithObjectID(id).
+			WithArguments(arguments).
+			WithReturnByValue(byValue).
+			WithObjectGroup(cdpObjectGroup).
+			Do(ctx)
+		if err == nil && exc != nil {
+			err = exc
+		}
+		return err
+	}))
+	return obj, err
+}
+
+// callValue calls the function and decodes its result into the value
+func (d *CDPDriver) callValue(id runtime.RemoteObjectID, fn string, value interface{}, args ...interface{}) error {
+	obj, err := d.call(id, fn, true, args...)
+	if err != nil {
+		return err
+	}
+	if len(obj.Value) == 0 {
+		return nil
+	}
+	return json.Unmarshal(obj.Value, value)
+}
+
+// ExecuteScript runs the body of a function, the arguments are in the arguments as in selenium
+func (d *CDPDriver) ExecuteScript(script string, args []interface{}) (interface{}, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	// a script without a result returns null as in selenium
+	expression := fmt.Sprintf("(function() {\n%s\n}).apply(null, %s)", script, data)
+	expression = fmt.Sprintf("(function(result) { return result === undefined ? null : result; })(%s)", expression)
+
+	var result interface{}
+	err = d.run(chromedp.Evaluate(expression, &result))
+	return result, err
+}
+
+// release frees the remote objects of the elements, the elements found
+// before can't be used afterwards
+func (d *CDPDriver) release() error {
+	return d.run(runtime.ReleaseObjectGroup(cdpObjectGroup))
+}
+
+// MoveTo moves the mouse to the center of the element
+func (d *CDPDriver) MoveTo(element selenium.WebElement) error {
+	return element.MoveTo(0, 0)
+}
+
+// Click clicks the button of the mouse where it's moved to
+func (d *CDPDriver) Click(button int) error {
+	d.mu.Lock()
+	x, y := d.mouseX, d.mouseY
+	d.mu.Unlock()
+	return d.click(x, y, mouseButton(button))
+}
+
+func (d *CDPDriver) click(x, y float64, button input.ButtonType) error {
+	return d.run(
+		input.DispatchMouseEvent(input.MouseMoved, x, y),
+		input.DispatchMouseEvent(input.MousePressed, x, y).WithButton(button).WithClickCount(1),
+		input.DispatchMouseEvent(input.MouseReleased, x, y).WithButton(button).WithClickCount(1),
+	)
+}
+
+func (d *CDPDriver) moveMouse(x, y float64) error {
+	d.mu.Lock()
+	d.mouseX, d.mouseY = x, y
+	d.mu.Unlock()
+	return d.run(input.DispatchMouseEvent(input.MouseMoved, x, y))
+}
+
+func mouseButton(button int) input.ButtonType {
+	switch button {
+	case selenium.MiddleButton:
+		return input.ButtonMiddle
+	case selenium.RightButton:
+		return input.ButtonRight
+	}
+	return input.ButtonLeft
+}
+
+// GetCookies returns the cookies of the page
+func (d *CDPDriver) GetCookies() ([]selenium.Cookie, error) {
+	var cookies []*network.Cookie
+	err := d.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		cookies, err = network.GetCookies().Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]selenium.Cookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		c := selenium.Cookie{
+			Name:   cookie.Name,
+			Value:  cookie.Value,
+			Path:   cookie.Path,
+			Domain: cookie.Domain,
+			Secure: cookie.Secure,
+		}
+		if !cookie.Session {
+			c.Expiry = uint(cookie.Expires)
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// AddCookie adds a cookie
+func (d *CDPDriver) AddCookie(cookie *selenium.Cookie) error {
+	return d.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		params := network.SetCookie(cookie.Name, cookie.Value).
+			WithDomain(cookie.Domain).
+			WithPath(cookie.Path).
+			WithSecure(cookie.Secure)
+		if cookie.Expiry > 0 {
+			expires := cdp.TimeSinceEpoch(time.Unix(int64(cookie.Expiry), 0))
+			params = params.WithExpires(&expires)
+		}
+		_, err := params.Do(ctx)
+		return err
+	}))
+}
+
+// DeleteAllCookies deletes all cookies of the browser
+func (d *CDPDriver) DeleteAllCookies() error {
+	return d.run(network.ClearBrowserCookies())
+}
+
+// WaitWithTimeout checks the condition until it's true or the time is out
+func (d *CDPDriver) WaitWithTimeout(condition Condition, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		done, err := condition(d)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf(waitTimeout, timeout)
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+}
+
+// SetPageLoadTimeout sets the timeout of Get
+func (d *CDPDriver) SetPageLoadTimeout(timeout time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadTimeout = timeout
+	return nil
+}
+
+// Quit closes the browser
+func (d *CDPDriver) Quit() error {
+	d.cancel()
+	d.allocCancel()
+	return nil
+}
+
+var _ selenium.WebElement = (*cdpElement)(nil)
+
+// cdpElement is an element of the page kept as a remote object
+type cdpElement struct {
+	driver *CDPDriver
+	id     runtime.RemoteObjectID
+}
+
+// the ways box scrolls the element into the view
+const (
+	scrollNone     = ""
+	scrollCenter   = "center"
+	scrollIfNeeded = "ifNeeded"
+)
+
+// cdpBox is the box of an element in the view and the scroll of the page
+type cdpBox struct {
+	Left, Top, Width, Height float64
+	ScrollX, ScrollY         float64
+}
+
+func (e *cdpElement) value(fn string, value interface{}, args ...interface{}) error {
+	return e.driver.callValue(e.id, fn, value, args...)
+}
+
+// box scrolls the element into the view as it's asked and returns its box
+func (e *cdpElement) box(scroll string) (cdpBox, error) {
+	var box cdpBox
+	err := e.value(`function(scroll) {
+		if (scroll == "center") {
+			this.scrollIntoView({block: "center", inline: "center"});
+		} else if (scroll == "ifNeeded") {
+			this.scrollIntoViewIfNeeded ? this.scrollIntoViewIfNeeded(true) : this.scrollIntoView();
+		}
+		var r = this.getBoundingClientRect();
+		return {left: r.left, top: r.top, width: r.width, height: r.height, scrollX: window.scrollX, scrollY: window.scrollY};
+	}`, &box, scroll)
+	return box, err
+}
+
+// center scrolls the element into the view and returns its center
+func (e *cdpElement) center() (float64, float64, error) {
+	box, err := e.box(scrollCenter)
+	return box.Left + box.Width/2, box.Top + box.Height/2, err
+}
+
+func (e *cdpElement) Click() error {
+	x, y, err := e.center()
+	if err != nil {
+		return err
+	}
+	return e.driver.click(x, y, input.ButtonLeft)
+}
+
+func (e *cdpElement) SendKeys(keys string) error {
+	if _, err := e.driver.call(e.id, `function() { this.focus(); }`, true); err != nil {
+		return err
+	}
+	return e.driver.run(chromedp.KeyEvent(keys))
+}
+
+func (e *cdpElement) Submit() error {
+	_, err := e.driver.call(e.id, `function() {
+		var form = this.form || this.closest("form");
+		if (form.requestSubmit) { form.requestSubmit(); } else { form.submit(); }
+	}`, true)
+	return err
+}
+
+func (e *cdpElement) Clear() error {
+	_, err := e.driver.call(e.id, `function() {
+		this.value = "";
+		this.dispatchEvent(new Event("input", {bubbles: true}));
+		this.dispatchEvent(new Event("change", {bubbles: true}));
+	}`, true)
+	return err
+}
+
+// MoveTo moves the mouse to the offset from the center of the element
+func (e *cdpElement) MoveTo(xOffset, yOffset int) error {
+	x, y, err := e.center()
+	if err != nil {
+		return err
+	}
+	return e.driver.moveMouse(x+float64(xOffset), y+float64(yOffset))
+}
+
+func (e *cdpElement) FindElement(by, value string) (selenium.WebElement, error) {
+	return e.driver.findElement(e.id, by, value)
+}
+
+func (e *cdpElement) FindElements(by, value string) ([]selenium.WebElement, error) {
+	return e.driver.findElements(e.id, by, value)
+}
+
+func (e *cdpElement) TagName() (string, error) {
+	var name string
+	err := e.value(`function() { return this.tagName.toLowerCase(); }`, &name)
+	return name, err
+}
+
+func (e *cdpElement) Text() (string, error) {
+	var text string
+	err := e.value(`function() { return this.innerText; }`, &text)
+	return text, err
+}
+
+func (e *cdpElement) IsSelected() (bool, error) {
+	var selected bool
+	err := e.value(`function() { return !!(this.checked || this.selected); }`, &selected)
+	return selected, err
+}
+
+func (e *cdpElement) IsEnabled() (bool, error) {
+	var enabled bool
+	err := e.value(`function() { return !this.disabled; }`, &enabled)
+	return enabled, err
+}
+
+func (e *cdpElement) IsDisplayed() (bool, error) {
+	var displayed bool
+	err := e.value(`function() {
+		var style = window.getComputedStyle(this);
+		return style.display != "none" && style.visibility != "hidden" && this.getClientRects().length > 0;
+	}`, &displayed)
+	return displayed, err
+}
+
+// GetAttribute returns the attribute or the property of the element as selenium does
+func (e *cdpElement) GetAttribute(name string) (string, error) {
+	var value *string
+	err := e.value(`function(name) {
+		// the fields report their current state as in selenium
+		if (name == "value" && "value" in this) { return String(this.value); }
+		if (name == "checked" || name == "selected") { return this[name] ? "true" : null; }
+		var value = this.getAttribute(name);
+		if (value === null && name in this) { value = this[name]; }
+		return value === null || value === undefined ? null : String(value);
+	}`, &value, name)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		return "", fmt.Errorf(attributeNotFound, name)
+	}
+	return *value, nil
+}
+
+func (e *cdpElement) Location() (*selenium.Point, error) {
+	point := &selenium.Point{}
+	err := e.value(`function() {
+		var r = this.getBoundingClientRect();
+		return {X: Math.round(r.left + window.scrollX), Y: Math.round(r.top + window.scrollY)};
+	}`, point)
+	return point, err
+}
+
+// LocationInView scrolls the element into the view if it's needed and returns
+// its location in the view
+func (e *cdpElement) LocationInView() (*selenium.Point, error) {
+	box, err := e.box(scrollIfNeeded)
+	if err != nil {
+		return nil, err
+	}
+	return &selenium.Point{X: int(math.Round(box.Left)), Y: int(math.Round(box.Top))}, nil
+}
+
+func (e *cdpElement) Size() (*selenium.Size, error) {
+	size := &selenium.Size{}
+	err := e.value(`function() {
+		var r = this.getBoundingClientRect();
+		return {Width: Math.round(r.width), Height: Math.round(r.height)};
+	}`, size)
+	return size, err
+}
+
+func (e *cdpElement) CSSProperty(name string) (string, error) {
+	var value string
+	err := e.value(`function(name) { return window.getComputedStyle(this).getPropertyValue(name); }`, &value, name)
+	return value, err
+}
+
+// Screenshot takes a PNG image of the element, it's scrolled into the view first if scroll is set
+func (e *cdpElement) Screenshot(scroll bool) ([]byte, error) {
+	mode := scrollNone
+	if scroll {
+		mode = scrollIfNeeded
+	}
+	box, err := e.box(mode)
+	if err != nil {
+		return nil, err
+	}
+
+	// the clip is in the coordinates of the page
+	clip := &page.Viewport{
+		X:      box.Left + box.ScrollX,
+		Y:      box.Top + box.ScrollY,
+		Width:  box.Width,
+		Height: box.Height,
+		Scale:  1,
+	}
+	var data []byte
+	err = e.driver.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		data, err = page.CaptureScreenshot().WithFormat(page.CaptureScreenshotFormatPng).WithClip(clip).Do(ctx)
+		return err
+	}))
+	return data, err
+}
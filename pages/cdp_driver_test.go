@@ -0,0 +1,149 @@
+package pages
+
+import (
+	"bytes"
+	"github.com/tebeka/selenium"
+	"testing"
+)
+
+func TestCDPFindElements(t *testing.T) {
+	driver := testBrowser(t)
+	openFixture(t, driver, "elements.html")
+
+	tests := []struct {
+		by, value string
+		count     int
+	}{
+		{selenium.ByCSSSelector, "#positions tr.row", 3},
+		{selenium.ByID, "item-2812647", 1},
+		// the id is escaped, it's not a class selector
+		{selenium.ByID, "item.dotted", 1},
+		{selenium.ByClassName, "other", 1},
+		{selenium.ByName, "quantity", 1},
+		{selenium.ByTagName, "td", 6},
+		{selenium.ByLinkText, "Close position", 1},
+		{selenium.ByPartialLinkText, "Close", 1},
+		{selenium.ByXPATH, "//tr[td[@class='qty' and text()='3']]", 1},
+		{selenium.ByCSSSelector, "tr.missing", 0},
+	}
+	for _, tt := range tests {
+		elements, err := driver.FindElements(tt.by, tt.value)
+		if err != nil {
+			t.Errorf("%s %s: %s", tt.by, tt.value, err)
+			continue
+		}
+		if len(elements) != tt.count {
+			t.Errorf("%s %s: %d elements, want %d", tt.by, tt.value, len(elements), tt.count)
+		}
+	}
+
+	if _, err := driver.FindElement(selenium.ByCSSSelector, "tr.missing"); err == nil {
+		t.Error("missing element is found")
+	}
+	// the elements are searched under the element only
+	row, err := driver.FindElement(selenium.ByID, "item-2812650")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := row.FindElement(selenium.ByCSSSelector, "td.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := name.Text(); text != "Tesla" {
+		t.Errorf("name of the row is %s, want Tesla", text)
+	}
+	cells, err := row.FindElements(selenium.ByTagName, "td")
+	if err != nil || len(cells) != 2 {
+		t.Errorf("%d cells of the row, want 2: %v", len(cells), err)
+	}
+	if qty, _ := cells[1].Text(); qty != "3" {
+		t.Errorf("elements are out of order, second cell is %s", qty)
+	}
+}
+
+func TestCDPGetAttribute(t *testing.T) {
+	driver := testBrowser(t)
+	openFixture(t, driver, "elements.html")
+
+	row, err := driver.FindElement(selenium.ByID, "item-2812647")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := row.GetAttribute("id"); err != nil || id != "item-2812647" {
+		t.Errorf("id is %s, %v", id, err)
+	}
+	if code, err := row.GetAttribute("data-code"); err != nil || code != "AAPL" {
+		t.Errorf("data-code is %s, %v", code, err)
+	}
+	if _, err := row.GetAttribute("data-missing"); err == nil {
+		t.Error("missing attribute is found")
+	}
+
+	// the value is the current one of the field
+	input, err := driver.FindElement(selenium.ByName, "quantity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := input.GetAttribute("value"); err != nil || value != "5" {
+		t.Errorf("value is %s, %v", value, err)
+	}
+	if err := input.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := input.GetAttribute("value"); err != nil || value != "" {
+		t.Errorf("value of the cleared field is %s, %v", value, err)
+	}
+
+	checkbox, err := driver.FindElement(selenium.ByName, "confirm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked, err := checkbox.GetAttribute("checked"); err != nil || checked != "true" {
+		t.Errorf("checked is %s, %v", checked, err)
+	}
+	// a property is returned if there is no attribute
+	if tabIndex, err := checkbox.GetAttribute("tabIndex"); err != nil || tabIndex != "0" {
+		t.Errorf("tabIndex property is %s, %v", tabIndex, err)
+	}
+}
+
+func TestCDPReleasesElements(t *testing.T) {
+	driver := testBrowser(t)
+	openFixture(t, driver, "elements.html")
+
+	row, err := driver.FindElement(selenium.ByID, "item-2812647")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := row.Text(); err == nil {
+		t.Error("element is kept after the release")
+	}
+}
+
+func TestCDPLocationInViewAndScreenshot(t *testing.T) {
+	driver := testBrowser(t)
+	openFixture(t, driver, "elements.html")
+
+	row, err := driver.FindElement(selenium.ByID, "item-2812647")
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := row.LocationInView()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.X < 0 || location.Y < 0 {
+		t.Errorf("location %v is out of the view", location)
+	}
+
+	image, err := row.Screenshot(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(image, []byte("\x89PNG")) {
+		t.Error("screenshot is not a PNG image")
+	}
+}
@@ -0,0 +1,85 @@
+package pages
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/tebeka/selenium"
+)
+
+// CookieJar keeps browser cookies in a file encrypted with AES-GCM
+type CookieJar struct {
+	Path   string
+	Secret string
+}
+
+// cookieFile is the encrypted content, cookies are only valid for their mode
+type cookieFile struct {
+	Mode    string            `json:"mode"`
+	Cookies []selenium.Cookie `json:"cookies"`
+}
+
+func (j *CookieJar) aead() (cipher.AEAD, error) {
+	if j.Secret == "" {
+		return nil, fmt.Errorf(cookieSecretEmpty)
+	}
+	key := sha256.Sum256([]byte(j.Secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Save encrypts cookies of the mode and writes them to the file
+func (j *CookieJar) Save(mode string, cookies []selenium.Cookie) error {
+	aead, err := j.aead()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(&cookieFile{Mode: mode, Cookies: cookies})
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	sealed := aead.Seal(nonce, nonce, data, nil)
+	return ioutil.WriteFile(j.Path, sealed, 0600)
+}
+
+// Load reads the file and decrypts cookies saved for the mode
+func (j *CookieJar) Load(mode string) ([]selenium.Cookie, error) {
+	aead, err := j.aead()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := ioutil.ReadFile(j.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf(cookieFileBroken, j.Path)
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf(cookieFileBroken, j.Path)
+	}
+	file := &cookieFile{}
+	err = json.Unmarshal(data, file)
+	if err != nil {
+		return nil, err
+	}
+	if file.Mode != mode {
+		return nil, fmt.Errorf(cookieModeMismatch, file.Mode, mode)
+	}
+	return file.Cookies, nil
+}
@@ -0,0 +1,119 @@
+package pages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/tebeka/selenium"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Selenium and CDP are the browser backends
+const (
+	Selenium = "selenium"
+	CDP      = "cdp"
+)
+
+// Condition is a state of the browser which is waited for
+type Condition func(d Driver) (bool, error)
+
+// Driver is the browser which the pages use. It's the selenium hub or a local
+// Chromium driven over the DevTools protocol, both return selenium elements.
+type Driver interface {
+	Get(url string) error
+	Title() (string, error)
+	FindElement(by, value string) (selenium.WebElement, error)
+	FindElements(by, value string) ([]selenium.WebElement, error)
+	ExecuteScript(script string, args []interface{}) (interface{}, error)
+	// MoveTo moves the mouse to the center of the element
+	MoveTo(element selenium.WebElement) error
+	// Click clicks the button of the mouse where it's moved to
+	Click(button int) error
+	GetCookies() ([]selenium.Cookie, error)
+	AddCookie(cookie *selenium.Cookie) error
+	DeleteAllCookies() error
+	WaitWithTimeout(condition Condition, timeout time.Duration) error
+	SetPageLoadTimeout(timeout time.Duration) error
+	Quit() error
+}
+
+// SeleniumDriver is the driver of a selenium hub
+type SeleniumDriver struct {
+	selenium.WebDriver
+	// HubURL is used to send the W3C actions
+	HubURL string
+	// W3CActions moves and clicks the mouse by the actions of the W3C protocol,
+	// geckodriver has no legacy endpoints of the mouse
+	W3CActions bool
+}
+
+// NewSeleniumDriver connects to the selenium hub
+func NewSeleniumDriver(caps selenium.Capabilities, hubURL string) (*SeleniumDriver, error) {
+	wd, err := selenium.NewRemote(caps, hubURL)
+	if err != nil {
+		return nil, err
+	}
+	return &SeleniumDriver{WebDriver: wd, HubURL: hubURL}, nil
+}
+
+// MoveTo moves the mouse to the center of the element
+func (d *SeleniumDriver) MoveTo(element selenium.WebElement) error {
+	if !d.W3CActions {
+		return element.MoveTo(0, 0)
+	}
+	// the element is marshalled as its W3C reference
+	origin, err := json.Marshal(element)
+	if err != nil {
+		return err
+	}
+	return d.performActions(
+		map[string]interface{}{"type": "pointerMove", "duration": 0, "origin": json.RawMessage(origin), "x": 0, "y": 0},
+	)
+}
+
+// Click clicks the button of the mouse where it's moved to
+func (d *SeleniumDriver) Click(button int) error {
+	if !d.W3CActions {
+		return d.WebDriver.Click(button)
+	}
+	// the buttons of selenium are numbered as the W3C ones
+	return d.performActions(
+		map[string]interface{}{"type": "pointerDown", "button": button},
+		map[string]interface{}{"type": "pointerUp", "button": button},
+	)
+}
+
+// performActions sends the actions of the mouse to the hub, the position of
+// the mouse is kept by the session between the calls
+func (d *SeleniumDriver) performActions(actions ...map[string]interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"actions": []interface{}{map[string]interface{}{
+			"type":       "pointer",
+			"id":         "mouse",
+			"parameters": map[string]string{"pointerType": "mouse"},
+			"actions":    actions,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/session/%s/actions", strings.TrimRight(d.HubURL, "/"), d.SessionID())
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(actionsFailed, resp.Status)
+	}
+	return nil
+}
+
+// WaitWithTimeout waits for the condition
+func (d *SeleniumDriver) WaitWithTimeout(condition Condition, timeout time.Duration) error {
+	return d.WebDriver.WaitWithTimeout(func(selenium.WebDriver) (bool, error) {
+		return condition(d)
+	}, timeout)
+}
@@ -0,0 +1,91 @@
+package pages
+
+import (
+	"encoding/json"
+	"github.com/tebeka/selenium"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// hubSession is a selenium session of a fake hub
+type hubSession struct {
+	selenium.WebDriver
+}
+
+func (s *hubSession) SessionID() string {
+	return "session-1"
+}
+
+// refElement is marshalled as the W3C reference of an element
+type refElement struct {
+	selenium.WebElement
+	id string
+}
+
+func (e *refElement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"element-6066-11e4-a52e-4f735466cecf": e.id})
+}
+
+type pointerActions struct {
+	Actions []struct {
+		Type       string `json:"type"`
+		Parameters struct {
+			PointerType string `json:"pointerType"`
+		} `json:"parameters"`
+		Actions []map[string]interface{} `json:"actions"`
+	} `json:"actions"`
+}
+
+func TestSeleniumDriverSendsW3CActions(t *testing.T) {
+	requests := make([]*pointerActions, 0)
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/wd/hub/session/session-1/actions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		actions := &pointerActions{}
+		if err := json.NewDecoder(r.Body).Decode(actions); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, actions)
+		w.Write([]byte(`{"value": null}`))
+	}))
+	defer hub.Close()
+
+	driver := &SeleniumDriver{WebDriver: &hubSession{}, HubURL: hub.URL + "/wd/hub", W3CActions: true}
+	if err := driver.MoveTo(&refElement{id: "row-7"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Click(selenium.RightButton); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("%d requests, want 2", len(requests))
+	}
+	move := requests[0].Actions[0]
+	if move.Type != "pointer" || move.Parameters.PointerType != "mouse" || len(move.Actions) != 1 {
+		t.Fatalf("move is %+v", move)
+	}
+	origin, _ := move.Actions[0]["origin"].(map[string]interface{})
+	if move.Actions[0]["type"] != "pointerMove" || origin["element-6066-11e4-a52e-4f735466cecf"] != "row-7" {
+		t.Errorf("move is to %v", move.Actions[0])
+	}
+	click := requests[1].Actions[0].Actions
+	if len(click) != 2 || click[0]["type"] != "pointerDown" || click[1]["type"] != "pointerUp" ||
+		click[0]["button"] != float64(2) || click[1]["button"] != float64(2) {
+		t.Errorf("click is %v", click)
+	}
+}
+
+func TestSeleniumDriverReportsFailedActions(t *testing.T) {
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer hub.Close()
+
+	driver := &SeleniumDriver{WebDriver: &hubSession{}, HubURL: hub.URL, W3CActions: true}
+	if err := driver.Click(selenium.LeftButton); err == nil {
+		t.Fatal("failed actions are not reported")
+	}
+}
@@ -0,0 +1,115 @@
+package pages
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Codes of the broker errors
+const (
+	CodeInsufficientFunds  = "insufficient_funds"
+	CodeMarketClosed       = "market_closed"
+	CodeMaxQuantity        = "max_quantity"
+	CodeMinQuantity        = "min_quantity"
+	CodeInvalidQuantity    = "invalid_quantity"
+	CodeInstrumentNotFound = "instrument_not_found"
+	CodePositionNotFound   = "position_not_found"
+	CodeOrderNotFound      = "order_not_found"
+	CodeSessionExpired     = "session_expired"
+	CodeAmbiguousKey       = "ambiguous_key"
+	CodeKeyNotFound        = "key_not_found"
+	CodeRejected           = "rejected"
+)
+
+// BrokerError is a failure reported by the platform
+type BrokerError struct {
+	// Code is a machine-readable reason
+	Code    string
+	Message string
+}
+
+func (e *BrokerError) Error() string {
+	return e.Message
+}
+
+var (
+	// ErrInsufficientFunds is returned when the account can't afford the operation
+	ErrInsufficientFunds = &BrokerError{Code: CodeInsufficientFunds, Message: insufficientFunds}
+	// ErrSessionExpired is returned when the platform has logged the account out
+	ErrSessionExpired = &BrokerError{Code: CodeSessionExpired, Message: sessionExpired}
+
+	digitsRe = regexp.MustCompile(`\d[\d ]*`)
+)
+
+func errMarketClosed(instrument, text string) *BrokerError {
+	msg := fmt.Sprintf(marketClosed, instrument)
+	if text != "" {
+		msg = fmt.Sprintf("%s. %s", msg, text)
+	}
+	return &BrokerError{Code: CodeMarketClosed, Message: msg}
+}
+
+func errInstrumentNotFound(instrument string) *BrokerError {
+	return &BrokerError{Code: CodeInstrumentNotFound, Message: fmt.Sprintf(instrumentNotFound, instrument)}
+}
+
+func errPositionNotFound(id string) *BrokerError {
+	return &BrokerError{Code: CodePositionNotFound, Message: fmt.Sprintf(positionNotFound, id)}
+}
+
+func errOrderNotFound(id string) *BrokerError {
+	return &BrokerError{Code: CodeOrderNotFound, Message: fmt.Sprintf(orderNotFound, id)}
+}
+
+func errAmbiguousKey(instrument string, ids []string) *BrokerError {
+	return &BrokerError{Code: CodeAmbiguousKey, Message: fmt.Sprintf(ambiguousKey, instrument, strings.Join(ids, ", "))}
+}
+
+func errKeyNotFound(instrument string, added int) *BrokerError {
+	return &BrokerError{Code: CodeKeyNotFound, Message: fmt.Sprintf(keyNotFound, instrument, added)}
+}
+
+// Unconfirmed reports whether the operation has been confirmed on the platform
+// but its guid is unknown
+func Unconfirmed(err error) bool {
+	e, ok := err.(*BrokerError)
+	return ok && (e.Code == CodeAmbiguousKey || e.Code == CodeKeyNotFound)
+}
+
+// decodeMessage maps a pop-up of the platform to the error catalog,
+// nil is returned for the messages which are not errors
+func decodeMessage(title, text, instrument string) *BrokerError {
+	switch {
+	case title == "Insufficient Funds":
+		return ErrInsufficientFunds
+	case title == "Maximum Quantity Limit":
+		return &BrokerError{Code: CodeMaxQuantity, Message: fmt.Sprintf(maxQtyLimit, firstNumber(text))}
+	case title == "Minimum Quantity Limit":
+		return &BrokerError{Code: CodeMinQuantity, Message: fmt.Sprintf(minQtyLimit, firstNumber(text))}
+	case title == "Market Closed" || strings.Contains(text, marketOpensAt):
+		return errMarketClosed(instrument, text)
+	case strings.Contains(text, sessionExpired):
+		return ErrSessionExpired
+	}
+	return nil
+}
+
+// rejected returns the catalog error of the pop-up or a generic rejection
+func rejected(title, text, instrument string) *BrokerError {
+	if err := decodeMessage(title, text, instrument); err != nil {
+		return err
+	}
+	msg := strings.TrimSpace(strings.Join([]string{title, text}, " "))
+	if msg == "" {
+		msg = operationRejected
+	}
+	return &BrokerError{Code: CodeRejected, Message: msg}
+}
+
+func firstNumber(text string) int {
+	str := strings.Replace(digitsRe.FindString(text), " ", "", -1)
+	qty, _ := strconv.Atoi(str)
+	return qty
+}
@@ -0,0 +1,223 @@
+package pages
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var errExecutorStopped = fmt.Errorf("browser executor is stopped")
+
+// Executor owns the browser session and runs operations one at a time
+type Executor struct {
+	driver Driver
+	jobs   chan *job
+	quit   chan struct{}
+	once   sync.Once
+}
+
+// releaser is a driver which keeps the objects of the page for an operation
+type releaser interface {
+	release() error
+}
+
+type job struct {
+	fn   func() error
+	done chan error
+}
+
+// NewExecutor creates an executor of the driver and starts its worker,
+// the driver may be nil if nothing has to be released after the operations
+func NewExecutor(driver Driver) *Executor {
+	e := &Executor{
+		driver: driver,
+		jobs:   make(chan *job),
+		quit:   make(chan struct{}),
+	}
+	go e.loop()
+	return e
+}
+
+func (e *Executor) loop() {
+	for {
+		select {
+		case j := <-e.jobs:
+			err := e.run(j.fn)
+			e.release()
+			j.done <- err
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+func (e *Executor) run(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("browser operation panicked: %v", r)
+			err = fmt.Errorf("browser operation failed: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// release frees the objects which the driver has kept for the operation
+func (e *Executor) release() {
+	r, ok := e.driver.(releaser)
+	if !ok {
+		return
+	}
+	if err := r.release(); err != nil {
+		log.Debug("Browser objects are not released: ", err)
+	}
+}
+
+// Do queues fn and waits until the worker has run it
+func (e *Executor) Do(fn func() error) error {
+	j := &job{fn: fn, done: make(chan error, 1)}
+	select {
+	case e.jobs <- j:
+	case <-e.quit:
+		return errExecutorStopped
+	}
+	return <-j.done
+}
+
+// Stop stops the worker, queued operations fail afterwards
+func (e *Executor) Stop() {
+	e.once.Do(func() {
+		close(e.quit)
+	})
+}
+
+// SerialBroker runs every call of the wrapped broker through an executor
+type SerialBroker struct {
+	broker   Broker
+	executor *Executor
+	session  *Session
+}
+
+// NewSerialBroker wraps broker so that its calls never overlap.
+// If session is not nil, it's restored before every call and an
+// operation interrupted by the session expiry is retried once.
+func NewSerialBroker(broker Broker, executor *Executor, session *Session) *SerialBroker {
+	return &SerialBroker{broker: broker, executor: executor, session: session}
+}
+
+func (b *SerialBroker) do(fn func() error) error {
+	return b.executor.Do(func() error {
+		if b.session == nil {
+			return fn()
+		}
+		if err := b.session.Ensure(); err != nil {
+			return err
+		}
+		err := fn()
+		if err != ErrSessionExpired {
+			return err
+		}
+		log.Warn("Operation is interrupted by the session expiry, retrying")
+		if err := b.session.Restore(); err != nil {
+			return err
+		}
+		return fn()
+	})
+}
+
+// Add adds a new position/order
+func (b *SerialBroker) Add(item *Item) (added *Item, err error) {
+	err = b.do(func() error {
+		added, err = b.broker.Add(item)
+		return err
+	})
+	return added, err
+}
+
+// GetPosition returns an opened position
+func (b *SerialBroker) GetPosition(id string) (position *Position, err error) {
+	err = b.do(func() error {
+		position, err = b.broker.GetPosition(id)
+		return err
+	})
+	return position, err
+}
+
+// GetPositions returns opened positions mapped by their guids, the missing ones are skipped
+func (b *SerialBroker) GetPositions(ids []string) (positions map[string]*Position, err error) {
+	err = b.do(func() error {
+		positions, err = b.broker.GetPositions(ids)
+		return err
+	})
+	return positions, err
+}
+
+// ListPositions returns all opened positions of the platform
+func (b *SerialBroker) ListPositions() (positions map[string]*Position, err error) {
+	err = b.do(func() error {
+		positions, err = b.broker.ListPositions()
+		return err
+	})
+	return positions, err
+}
+
+// EditPosition edits an opened position
+func (b *SerialBroker) EditPosition(item *DbItem, payload *PositionPayload) (edited *DbItem, err error) {
+	err = b.do(func() error {
+		edited, err = b.broker.EditPosition(item, payload)
+		return err
+	})
+	return edited, err
+}
+
+// ClosePosition closes the whole or a part of an opened position
+func (b *SerialBroker) ClosePosition(item *DbItem, payload *ClosePayload) (closed *DbItem, err error) {
+	err = b.do(func() error {
+		closed, err = b.broker.ClosePosition(item, payload)
+		return err
+	})
+	return closed, err
+}
+
+// EditLimits edits the limits of an opened position
+func (b *SerialBroker) EditLimits(id string, payload *LimitsPayload) (position *Position, err error) {
+	err = b.do(func() error {
+		position, err = b.broker.EditLimits(id, payload)
+		return err
+	})
+	return position, err
+}
+
+// DeletePosition deletes an opened position
+func (b *SerialBroker) DeletePosition(id string) (position *Position, err error) {
+	err = b.do(func() error {
+		position, err = b.broker.DeletePosition(id)
+		return err
+	})
+	return position, err
+}
+
+// GetOrder returns a pending order
+func (b *SerialBroker) GetOrder(id string) (order *Order, err error) {
+	err = b.do(func() error {
+		order, err = b.broker.GetOrder(id)
+		return err
+	})
+	return order, err
+}
+
+// GetOrders returns all pending orders mapped by their guids
+func (b *SerialBroker) GetOrders() (orders map[string]*Order, err error) {
+	err = b.do(func() error {
+		orders, err = b.broker.GetOrders()
+		return err
+	})
+	return orders, err
+}
+
+// DeleteOrder deletes a pending order
+func (b *SerialBroker) DeleteOrder(id string) error {
+	return b.do(func() error {
+		return b.broker.DeleteOrder(id)
+	})
+}
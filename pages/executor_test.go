@@ -0,0 +1,151 @@
+package pages
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingBroker records how many calls run at the same time
+type countingBroker struct {
+	*MemoryBroker
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (b *countingBroker) GetOrders() (map[string]*Order, error) {
+	b.mu.Lock()
+	b.running++
+	if b.running > b.max {
+		b.max = b.running
+	}
+	b.mu.Unlock()
+
+	orders, err := b.MemoryBroker.GetOrders()
+
+	b.mu.Lock()
+	b.running--
+	b.mu.Unlock()
+	return orders, err
+}
+
+func TestSerialBrokerRunsCallsOneAtATime(t *testing.T) {
+	executor := NewExecutor(nil)
+	defer executor.Stop()
+	broker := &countingBroker{MemoryBroker: NewMemoryBroker()}
+	serial := NewSerialBroker(broker, executor, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := serial.GetOrders(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if broker.max != 1 {
+		t.Fatalf("calls overlapped: %d at once", broker.max)
+	}
+}
+
+func TestSerialBrokerFailsAfterStop(t *testing.T) {
+	executor := NewExecutor(nil)
+	serial := NewSerialBroker(NewMemoryBroker(), executor, nil)
+	executor.Stop()
+	if _, err := serial.GetOrders(); err != errExecutorStopped {
+		t.Fatalf("got %v, want %v", err, errExecutorStopped)
+	}
+}
+
+// releasingDriver counts the releases of the executor
+type releasingDriver struct {
+	Driver
+	releases int
+}
+
+func (d *releasingDriver) release() error {
+	d.releases++
+	return nil
+}
+
+func TestExecutorReleasesDriverAfterOperation(t *testing.T) {
+	driver := &releasingDriver{}
+	executor := NewExecutor(driver)
+	defer executor.Stop()
+
+	for i := 0; i < 3; i++ {
+		released := driver.releases
+		err := executor.Do(func() error {
+			if driver.releases != released {
+				t.Error("driver is released during the operation")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if driver.releases != 3 {
+		t.Errorf("%d releases, want 3", driver.releases)
+	}
+}
+
+func TestExecutorRunsOneAtATime(t *testing.T) {
+	executor := NewExecutor(nil)
+	defer executor.Stop()
+
+	var mu sync.Mutex
+	running, max := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := executor.Do(func() error {
+				mu.Lock()
+				running++
+				if running > max {
+					max = running
+				}
+				mu.Unlock()
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if max != 1 {
+		t.Fatalf("operations overlapped: %d at once", max)
+	}
+}
+
+func TestExecutorRecoversPanic(t *testing.T) {
+	executor := NewExecutor(nil)
+	defer executor.Stop()
+
+	if err := executor.Do(func() error { panic("no element") }); err == nil {
+		t.Fatal("panic is not returned as an error")
+	}
+	// the worker keeps running after the panic
+	if err := executor.Do(func() error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecutorFailsAfterStop(t *testing.T) {
+	executor := NewExecutor(nil)
+	executor.Stop()
+	if err := executor.Do(func() error { return nil }); err != errExecutorStopped {
+		t.Fatalf("got %v, want %v", err, errExecutorStopped)
+	}
+}
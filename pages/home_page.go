@@ -4,12 +4,17 @@ import (
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/tebeka/selenium"
+	"strings"
 	"time"
 )
 
 // HomePage contains a home page
 type HomePage struct {
 	Page Page
+	// Mode selects the login form (demo or real), real if empty
+	Mode string
+	// TOTPSecret is used to pass the two-factor challenge
+	TOTPSecret string
 }
 
 // GoToAccountPage directs to account page
@@ -29,35 +34,151 @@ func (p *HomePage) GoToAccountPage() (*AccountPage, error) {
 }
 
 // LoginToAccount logins to access an account page
-func (p *HomePage) LoginToAccount(login, pswd string) *AccountPage {
+func (p *HomePage) LoginToAccount(login, pswd string) (*AccountPage, error) {
 	title, _ := p.Page.Driver.Title()
 	log.Info(fmt.Sprintf("login page: %s", title))
 
-	p.Page.FindElementByID(domPaths["login_id"]).SendKeys(login)
-	p.Page.FindElementByID(domPaths["password_id"]).SendKeys(pswd)
+	if p.isMaintenance() {
+		log.Warn(ErrMaintenance.Error())
+		return nil, ErrMaintenance
+	}
+	mode := p.Mode
+	if mode == "" {
+		mode = REAL
+	}
+	loginInput := p.Page.FindElementByID(fmt.Sprintf(domPaths["login_id"], mode))
+	pswdInput := p.Page.FindElementByID(fmt.Sprintf(domPaths["password_id"], mode))
 	loginbtn := p.Page.FindElementByCSS(domPaths["login_btn"])
+	if loginInput == nil || pswdInput == nil || loginbtn == nil {
+		log.Warn(ErrLoginForm.Error())
+		return nil, ErrLoginForm
+	}
+	loginInput.SendKeys(login)
+	pswdInput.SendKeys(pswd)
 	loginbtn.Click()
-	//time.Sleep(time.Millisecond * 3000)
 
-	// check if we really were redirected to account page
-	err := p.Page.Driver.WaitWithTimeout(p.Page.Displayed(selenium.ByCSSSelector, domPaths["nav_logo"]), time.Second*10)
-	if err != nil {
+	// wait until we are redirected to account page or the login is rejected
+	err := p.Page.Driver.WaitWithTimeout(p.loginFinished, time.Second*10)
+	if p.isShown("otp_input") {
+		if err := p.passTwoFactor(); err != nil {
+			log.WithField("code", err.Code).Warn(err.Error())
+			return nil, err
+		}
+	}
+	if p.Page.FindElementByCSS(domPaths["nav_logo"]) == nil {
 		title, _ = p.Page.Driver.Title()
 		log.Info(fmt.Sprintf("current page: %s", title))
-		log.Printf(err.Error())
-		return nil
+		loginErr := p.loginFailure()
+		if err != nil {
+			log.Debug(err.Error())
+		}
+		log.WithField("code", loginErr.Code).Warn(loginErr.Error())
+		return nil, loginErr
 	}
 	title, _ = p.Page.Driver.Title()
-	log.Info(fmt.Sprintf("logged in as %s, page: %s", login, title))
+	log.Info(fmt.Sprintf("logged in as %s (%s), page: %s", login, mode, title))
 
+	if mode == DEMO {
+		p.closeDemoPopups()
+	}
+	return &AccountPage{Page: p.Page}, nil
+}
+
+// closeDemoPopups closes the pop-ups shown only on the demo account
+func (p *HomePage) closeDemoPopups() {
 	//check if it's a weekend
 	d := time.Now().Weekday()
-	if /*mode == "demo"*/ time.Sunday <= d && d <= time.Saturday {
+	if d == time.Saturday || d == time.Sunday {
 		we := p.Page.FindElementByCSS(domPaths["alert_box"])
 		if we != nil {
 			we.Click()
 			log.Debug("weekend trading alert-box closed")
 		}
 	}
-	return &AccountPage{Page: p.Page}
+	if we := p.Page.FindElementByCSS(domPaths["demo_popup"]); we != nil {
+		we.Click()
+		log.Debug("demo account pop-up closed")
+	}
+}
+
+// loginFinished is a wait condition which is met when the login is either
+// accepted or rejected
+func (p *HomePage) loginFinished(wd Driver) (bool, error) {
+	for _, path := range []string{"nav_logo", "login_error", "captcha", "otp_input"} {
+		if p.isShown(path) {
+			return true, nil
+		}
+	}
+	return p.isMaintenance(), nil
+}
+
+// isShown checks if the element is on the page and visible
+func (p *HomePage) isShown(path string) bool {
+	we := p.Page.FindElementByCSS(domPaths[path])
+	if we == nil {
+		return false
+	}
+	shown, _ := we.IsDisplayed()
+	return shown
+}
+
+// passTwoFactor submits a TOTP code on the two-factor challenge page
+func (p *HomePage) passTwoFactor() *LoginError {
+	log.Info("two-factor code is requested")
+	if p.TOTPSecret == "" {
+		return ErrTwoFactorRequired
+	}
+	code, err := TOTP(p.TOTPSecret, time.Now())
+	if err != nil {
+		log.Error(err.Error())
+		return ErrTwoFactorRequired
+	}
+	input := p.Page.FindElementByCSS(domPaths["otp_input"])
+	if input == nil {
+		return ErrLoginForm
+	}
+	input.Clear()
+	input.SendKeys(code)
+	if submit := p.Page.FindElementByCSS(domPaths["otp_submit"]); submit != nil {
+		submit.Click()
+	} else {
+		input.Submit()
+	}
+
+	err = p.Page.Driver.WaitWithTimeout(func(wd Driver) (bool, error) {
+		return p.isShown("nav_logo") || p.isShown("otp_error"), nil
+	}, time.Second*10)
+	if err != nil {
+		log.Debug(err.Error())
+	}
+	if p.Page.FindElementByCSS(domPaths["nav_logo"]) != nil {
+		log.Debug("two-factor code is accepted")
+		return nil
+	}
+	if p.Page.FindElementByCSS(domPaths["otp_error"]) != nil {
+		return ErrTwoFactorRejected
+	}
+	return ErrLoginTimeout
+}
+
+// loginFailure decodes why the login page wasn't left
+func (p *HomePage) loginFailure() *LoginError {
+	if p.Page.FindElementByCSS(domPaths["captcha"]) != nil {
+		return ErrCaptcha
+	}
+	if p.Page.FindElementByCSS(domPaths["login_error"]) != nil {
+		return ErrWrongCredentials
+	}
+	if p.isMaintenance() {
+		return ErrMaintenance
+	}
+	return ErrLoginTimeout
+}
+
+func (p *HomePage) isMaintenance() bool {
+	title, _ := p.Page.Driver.Title()
+	if strings.Contains(strings.ToLower(title), maintenance) {
+		return true
+	}
+	return p.Page.FindElementByCSS(domPaths["maintenance"]) != nil
 }
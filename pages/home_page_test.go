@@ -0,0 +1,68 @@
+package pages
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// testBrowser starts a headless Chromium, the test is skipped if there is none.
+// CHROME_PATH points to the binary if it's not in the PATH.
+func testBrowser(t *testing.T) *CDPDriver {
+	t.Helper()
+	path := os.Getenv("CHROME_PATH")
+	if path == "" {
+		for _, name := range []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable"} {
+			if found, err := exec.LookPath(name); err == nil {
+				path = found
+				break
+			}
+		}
+	}
+	if path == "" {
+		t.Skip("Chromium is not found, set CHROME_PATH")
+	}
+	driver, err := NewCDPDriver(path, []string{"--no-sandbox"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { driver.Quit() })
+	return driver
+}
+
+// openFixture opens a page of the testdata
+func openFixture(t *testing.T, driver Driver, name string) {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Get("file://" + path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoginPassesTwoFactor(t *testing.T) {
+	driver := testBrowser(t)
+	openFixture(t, driver, "login_2fa.html")
+
+	home := &HomePage{Page: Page{Driver: driver}, TOTPSecret: rfcSecret}
+	if _, err := home.LoginToAccount("user", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if !home.isShown("nav_logo") {
+		t.Error("account page is not shown")
+	}
+}
+
+func TestLoginWithoutSecret(t *testing.T) {
+	driver := testBrowser(t)
+	openFixture(t, driver, "login_2fa.html")
+
+	home := &HomePage{Page: Page{Driver: driver}}
+	_, err := home.LoginToAccount("user", "password")
+	if err != ErrTwoFactorRequired {
+		t.Fatalf("got %v, want %v", err, ErrTwoFactorRequired)
+	}
+}
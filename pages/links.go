@@ -6,12 +6,19 @@ var (
 
 func getDomPaths() map[string]string {
 	return map[string]string{
-		"login_id":            "username-real",
-		"password_id":         "pass-real",
+		"login_id":            "username-%s",
+		"password_id":         "pass-%s",
 		"login_btn":           "input.button-login",
 		"ok":                  "div.buttons > span.btn.btn-primary",
 		"nav_logo":            "div.nav_logo",
+		"login_error":         "div.login-form-error",
+		"captcha":             "iframe[src*='recaptcha']",
+		"maintenance":         "div.maintenance-page",
+		"otp_input":           "div.two-factor-auth input[name='code']",
+		"otp_submit":          "div.two-factor-auth input.button-login",
+		"otp_error":           "div.two-factor-auth div.two-factor-error",
 		"alert_box":           "#weekend-trading-popup > span.weekend-trading-close",
+		"demo_popup":          "div.demo-account-popup span.close-icon",
 		"add_order":           "#positionsTable > span.open-dialog-icon.svg-icon-holder",
 		"dt_no_data":          "span.dataTable-no-data-action",
 		"search_box":          "#searchlist > div.searchbox > input[type=text]",
@@ -32,20 +39,39 @@ func getDomPaths() map[string]string {
 		"ls_tp_toggle":        "#limit_stop-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.take-profit-container > div.take-profit-toggle",
 		"ls_sl_toggle":        "#limit_stop-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.stop-loss-container > div.stop-loss-toggle",
 		"market_tp_toggle":    "#market-order-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.take-profit-container > div.take-profit-toggle",
-		"market_sl_toggle":    "#market-order-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.take-profit-container > div.stop-loss-toggle",
+		"market_sl_toggle":    "#market-order-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.stop-loss-container > div.stop-loss-toggle",
 		"market_sl_down":      "#market-order-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.stop-loss-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-down.svg-icon-holder",
 		"market_sl_up":        "#market-order-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.stop-loss-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-up.svg-icon-holder",
 		"market_tp_down":      "#market-order-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.take-profit-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-down.svg-icon-holder",
 		"market_tp_up":        "#market-order-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.take-profit-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-up.svg-icon-holder",
+		"ls_sl_down":          "#limit_stop-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.stop-loss-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-down.svg-icon-holder",
+		"ls_sl_up":            "#limit_stop-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.stop-loss-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-up.svg-icon-holder",
+		"ls_tp_down":          "#limit_stop-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.take-profit-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-down.svg-icon-holder",
+		"ls_tp_up":            "#limit_stop-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.take-profit-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-up.svg-icon-holder",
+		"limit_box":           "#%s-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.%s-container > div.limitstop",
+		"limit_tab":           "div.limitstop-tabs > span.%s",
+		"limit_input":         "div.%s-container div.visible-input > input",
 		"confirm_btn":         "div.button-container > div.confirm-button",
 		"ok_btn":              "div.buttons > span.btn.btn-primary",
 		"tab_positions":       "span.tab-item.tabpositions",
 		"tab_orders":          "span.tab-item.taborders",
-		"positions_last_row":  "#positionsTable > div.scrollable-area.scrollable-area-at-top > div.scrollable-area-body > div > table > tbody > tr:last-child",
-		"orders_last_row":     "#ordersTable > div.scrollable-area.scrollable-area-at-top > div.scrollable-area-body > div > table > tbody > tr:last-child",
+		"orders_rows":         "#ordersTable > div.scrollable-area > div.scrollable-area-body > div > table > tbody > tr",
+		"positions_table":     "#positionsTable",
+		"positions_rows":      "#positionsTable > div.scrollable-area > div.scrollable-area-body > div > table > tbody > tr",
+		"cell_avgprice":       "td.averagePrice",
+		"cell_ts":             "td.trailingStop",
+		"cell_margin":         "td.margin",
+		"cell_name":           "td.name",
+		"cell_qty":            "td.quantity",
+		"cell_dir":            "td.direction",
+		"cell_type":           "td.type",
+		"cell_price":          "td.price",
+		"cell_curprice":       "td.currentPrice",
+		"cell_tp":             "td.limitPrice",
+		"cell_sl":             "td.stopPrice",
+		"cell_created":        "td.created",
 		"cxtmenu":             "div.contextmenu",
 		"rm_item":             "div.item-%s-contextmenu-remove",
-		"date_created":        "#positionsTable > div.dataTable-header > table > thead > tr > th.created",
 		"settings":            "#positionsTable > span.column-settings-icon",
 		"dt_ctxmenu":          "//*[@id='datatable-contextmenu']",
 		"ctx_name":            "div.item.item-datatable-contextmenu-name",
@@ -73,6 +99,16 @@ func getDomPaths() map[string]string {
 		"info_close":          "div.header > div.close-icon",
 		"dlg":                 "div.window",
 		"market_order_tab":    "div.scrollable-area-content > div.tab-control > span:nth-child(1)",
+		"limit_stop_tab":      "div.scrollable-area-content > div.tab-control > span:nth-child(2)",
+		"ls_price_input":      "#limit_stop-price div.visible-input > input",
+		"limits_tab":          "div.scrollable-area-content > div.tab-control > span:nth-child(3)",
+		"limit_toggle":        "#%s-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.%s-container > div.%s-toggle",
+		"position_sl_down":    "#position-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.stop-loss-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-down.svg-icon-holder",
+		"position_sl_up":      "#position-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.stop-loss-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-up.svg-icon-holder",
+		"position_tp_down":    "#position-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.take-profit-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-down.svg-icon-holder",
+		"position_tp_up":      "#position-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.take-profit-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-up.svg-icon-holder",
+		"position_ts_down":    "#position-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.trailing-stop-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-down.svg-icon-holder",
+		"position_ts_up":      "#position-profitloss > div.scrollable-area > div.scrollable-area-body > div > div.trailing-stop-container > div.limitstop > div.distance-container > div.distance-spinner > div.spinner-arrow-container > div.spinner-arrow.spinner-up.svg-icon-holder",
 		"info_tab":            "div.scrollable-area-content > div.tab-control > span:nth-child(4)",
 		"qty_value":           "div.position-quantity-and-price",
 		"qty_input_xpath":     "/html/body/div[8]/div[2]/div[3]/div[1]/div[1]/div[3]/div/div[2]/div[3]/div[1]/div[2]/div[2]/input",
@@ -0,0 +1,33 @@
+package pages
+
+// LoginError describes why the login has failed
+type LoginError struct {
+	// Code is a machine-readable reason
+	Code    string
+	Message string
+	// Retry tells whether another attempt could succeed
+	Retry bool
+}
+
+func (e *LoginError) Error() string {
+	return e.Message
+}
+
+var (
+	// ErrWrongCredentials is returned when the login or password is rejected
+	ErrWrongCredentials = &LoginError{Code: "wrong_credentials", Message: "Wrong login or password"}
+	// ErrCaptcha is returned when the platform asks to solve a captcha
+	ErrCaptcha = &LoginError{Code: "captcha", Message: "Captcha is shown on the login page", Retry: true}
+	// ErrMaintenance is returned when the platform is under maintenance
+	ErrMaintenance = &LoginError{Code: "maintenance", Message: "Platform is under maintenance", Retry: true}
+	// ErrLoginTimeout is returned when the account page hasn't been opened in time
+	ErrLoginTimeout = &LoginError{Code: "timeout", Message: "Account page hasn't been opened in time", Retry: true}
+	// ErrLoginForm is returned when the login form is not found
+	ErrLoginForm = &LoginError{Code: "login_form_not_found", Message: "Login form is not found", Retry: true}
+	// ErrTwoFactorRequired is returned when 2FA is asked but no TOTP secret is configured
+	ErrTwoFactorRequired = &LoginError{Code: "two_factor_required", Message: "Two-factor code is required, TOTP secret is not set"}
+	// ErrTwoFactorRejected is returned when the platform rejects the two-factor code
+	ErrTwoFactorRejected = &LoginError{Code: "two_factor_rejected", Message: "Two-factor code is rejected", Retry: true}
+	// ErrNotLoggedIn is returned while the service is waiting for a login
+	ErrNotLoggedIn = &LoginError{Code: "not_logged_in", Message: "Not logged in yet", Retry: true}
+)
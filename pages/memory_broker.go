@@ -0,0 +1,238 @@
+package pages
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryBroker is an in-memory Broker which doesn't need a browser session
+type MemoryBroker struct {
+	mu        sync.Mutex
+	seq       int
+	positions map[string]*Position
+	orders    map[string]*Order
+}
+
+// NewMemoryBroker creates an empty in-memory broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		positions: make(map[string]*Position, 0),
+		orders:    make(map[string]*Order, 0),
+	}
+}
+
+func (b *MemoryBroker) nextKey() string {
+	b.seq++
+	return strconv.Itoa(b.seq)
+}
+
+// Add adds a new position/order
+func (b *MemoryBroker) Add(item *Item) (*Item, error) {
+	if item == nil {
+		return nil, fmt.Errorf(inputDataErrors)
+	}
+	if item.Direction != BUY && item.Direction != SELL {
+		return nil, fmt.Errorf(unacceptableValue, item.Direction)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	item.Key = b.nextKey()
+	base := BasePosition{
+		Instrument:   item.Instrument,
+		Quantity:     item.Qty,
+		Direction:    item.Direction,
+		Price:        item.Price,
+		CurrentPrice: item.Price,
+		DateCreated:  time.Now().Format(time.RFC3339),
+	}
+	if item.IsOrder {
+		item.Type = "limit"
+		b.orders[item.Key] = &Order{BasePosition: base, Type: item.Type}
+	} else {
+		b.positions[item.Key] = &Position{BasePosition: base}
+	}
+	return item, nil
+}
+
+// GetPosition returns an opened position
+func (b *MemoryBroker) GetPosition(id string) (*Position, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	position, ok := b.positions[id]
+	if !ok {
+		return nil, errPositionNotFound(id)
+	}
+	copied := *position
+	return &copied, nil
+}
+
+// GetPositions returns opened positions mapped by their guids
+func (b *MemoryBroker) GetPositions(ids []string) (map[string]*Position, error) {
+	positions := make(map[string]*Position, len(ids))
+	for _, id := range ids {
+		position, err := b.GetPosition(id)
+		if err != nil {
+			continue
+		}
+		positions[id] = position
+	}
+	return positions, nil
+}
+
+// ListPositions returns all opened positions mapped by their guids
+func (b *MemoryBroker) ListPositions() (map[string]*Position, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	positions := make(map[string]*Position, len(b.positions))
+	for id, position := range b.positions {
+		copied := *position
+		positions[id] = &copied
+	}
+	return positions, nil
+}
+
+// EditPosition edits an opened position
+func (b *MemoryBroker) EditPosition(item *DbItem, payload *PositionPayload) (*DbItem, error) {
+	if payload == nil {
+		return nil, fmt.Errorf(inputDataErrors)
+	}
+	if payload.Direction != SELL && payload.Direction != BUY {
+		return nil, fmt.Errorf(directionNotDefined)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	position, ok := b.positions[item.GUID]
+	if !ok {
+		return nil, errPositionNotFound(item.GUID)
+	}
+	dlg := &orderWindow{}
+	qty, err := dlg.calcQuantity(payload, position.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Direction != position.Direction {
+		qty = -qty
+	}
+	position.Quantity += qty
+	item.Qty = position.Quantity
+
+	return item, nil
+}
+
+// ClosePosition closes the whole or a part of an opened position
+func (b *MemoryBroker) ClosePosition(item *DbItem, payload *ClosePayload) (*DbItem, error) {
+	if payload == nil {
+		return nil, fmt.Errorf(inputDataErrors)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	position, ok := b.positions[item.GUID]
+	if !ok {
+		return nil, errPositionNotFound(item.GUID)
+	}
+	qty, err := closeQuantity(payload, position.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	position.Quantity -= qty
+	if position.Quantity == 0 {
+		delete(b.positions, item.GUID)
+		item.ClosePrice = position.CurrentPrice
+		item.Result = position.Result
+	}
+	item.Qty = position.Quantity
+	return item, nil
+}
+
+// EditLimits sets, moves or removes the limits of an opened position
+func (b *MemoryBroker) EditLimits(id string, payload *LimitsPayload) (*Position, error) {
+	if payload == nil {
+		return nil, fmt.Errorf(inputDataErrors)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	position, ok := b.positions[id]
+	if !ok {
+		return nil, errPositionNotFound(id)
+	}
+	setLimit := func(value *string, limit *Limit) {
+		if limit == nil {
+			return
+		}
+		*value = ""
+		if limit.IsUse {
+			_, v := limitValue(limit)
+			*value = formatFloat(v)
+		}
+	}
+	setLimit(&position.TakeProfit, payload.TakeProfit)
+	setLimit(&position.StopLoss, payload.StopLoss)
+	setLimit(&position.TrailingStop, payload.TrailingStop)
+
+	copied := *position
+	return &copied, nil
+}
+
+// DeletePosition deletes an opened position
+func (b *MemoryBroker) DeletePosition(id string) (*Position, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	position, ok := b.positions[id]
+	if !ok {
+		return nil, errPositionNotFound(id)
+	}
+	delete(b.positions, id)
+	copied := *position
+	return &copied, nil
+}
+
+// GetOrder returns a pending order
+func (b *MemoryBroker) GetOrder(id string) (*Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	order, ok := b.orders[id]
+	if !ok {
+		return nil, errOrderNotFound(id)
+	}
+	copied := *order
+	return &copied, nil
+}
+
+// GetOrders returns all pending orders mapped by their guids
+func (b *MemoryBroker) GetOrders() (map[string]*Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	orders := make(map[string]*Order, len(b.orders))
+	for id, order := range b.orders {
+		copied := *order
+		orders[id] = &copied
+	}
+	return orders, nil
+}
+
+// DeleteOrder deletes a pending order
+func (b *MemoryBroker) DeleteOrder(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.orders[id]; !ok {
+		return errOrderNotFound(id)
+	}
+	delete(b.orders, id)
+	return nil
+}
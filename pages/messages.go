@@ -1,23 +1,42 @@
 package pages
 
 var (
-	inputDataErrors      = "Input data is not initialized"
-	instrumentNotDefined = "Instrument is not defined"
-	instrumentNotFound   = "Instrument '%s' is not found"
-	directionNotDefined  = "Direction is not defined"
-	typeNotDefined       = "Type is not defined"
-	wrongTypeInput       = "Wrong data type input: '#v'"
-	dialogNotOpened      = "Window hasn't been opened yet"
-	foundProductAtPos    = "Found product at position %d"
-	maxQtyLimit          = "Max quantity reached, need to be below %d"
-	minQtyLimit          = "Min quantity reached, need to be above %d"
-	unacceptableValue    = "Unacceptable value: %s"
-	marketClosed         = "Market closed for %s"
-	cssError             = "Css element `%s` is not found"
-	positionNotFound     = "Position is not found, id: %s"
-	sessionExpired       = "Session has expired"
-	positionTableEmpty   = "Position table is empty"
-	marketOpensAt        = "This market opens at"
+	inputDataErrors        = "Input data is not initialized"
+	instrumentNotDefined   = "Instrument is not defined"
+	instrumentNotFound     = "Instrument '%s' is not found"
+	directionNotDefined    = "Direction is not defined"
+	typeNotDefined         = "Type is not defined"
+	wrongTypeInput         = "Wrong data type input: '#v'"
+	dialogNotOpened        = "Window hasn't been opened yet"
+	foundProductAtPos      = "Found product at position %d"
+	maxQtyLimit            = "Max quantity reached, need to be below %d"
+	minQtyLimit            = "Min quantity reached, need to be above %d"
+	unacceptableValue      = "Unacceptable value: %s"
+	marketClosed           = "Market closed for %s"
+	insufficientFunds      = "Insufficient funds"
+	operationRejected      = "Operation is rejected"
+	limitNotDefined        = "Value of the %s limit is not defined"
+	limitNotSet            = "The %s %s is %s instead of %s"
+	closeQtyInvalid        = "Can't close %d of the current quantity %d"
+	cssError               = "Css element `%s` is not found"
+	positionNotFound       = "Position is not found, id: %s"
+	orderNotFound          = "Order is not found, id: %s"
+	sessionExpired         = "Session has expired"
+	maintenance            = "maintenance"
+	cookieSecretEmpty      = "Secret of the cookie file is not set"
+	cookieFileBroken       = "Cookie file `%s` can't be decrypted"
+	totpSecretInvalid      = "TOTP secret is not a valid base32 string"
+	cookieModeMismatch     = "Cookies are saved for %s mode, not for %s"
+	ambiguousKey           = "Several new rows of %s are found: %s"
+	keyNotFound            = "New row of %s is not found, %d rows are added"
+	tableNotRendered       = "Table `%s` has no rows and no placeholder of the empty table"
+	unexpectedScriptResult = "Unexpected result of the script: %v"
+	devToolsFailed         = "DevTools command has failed: %s"
+	elementNotFound        = "Element is not found by %s: %s"
+	attributeNotFound      = "Attribute %s is not found"
+	waitTimeout            = "Condition is not met in %s"
+	actionsFailed          = "Actions are not performed: %s"
+	marketOpensAt          = "This market opens at"
 	// GUIDNotFound (guid is not found)
 	GUIDNotFound  = "Guid of `%d` item is not found"
 	buyNotAllowed = "Cannot buy more than it's possible"
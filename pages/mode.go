@@ -0,0 +1,71 @@
+package pages
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+const (
+	// DEMO const
+	DEMO = "demo"
+	// REAL const
+	REAL = "real"
+)
+
+// accountHosts maps a mode to the subdomain of its account page
+var accountHosts = map[string]string{
+	DEMO: "demo",
+	REAL: "live",
+}
+
+// ValidMode checks if the mode is known
+func ValidMode(mode string) bool {
+	return mode == DEMO || mode == REAL
+}
+
+// ModeHook adds the current mode of the sessions to every log entry
+type ModeHook struct {
+	Sessions []*Session
+}
+
+// Levels returns all levels, every entry gets the mode
+func (h *ModeHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire adds the mode of the account of the entry, it's the only session or
+// the one named by the account field. The entries of no account get the modes
+// of all sessions, the mode itself if they are all in the same one.
+func (h *ModeHook) Fire(entry *log.Entry) error {
+	if len(h.Sessions) == 0 {
+		return nil
+	}
+	if name, ok := entry.Data["account"].(string); ok {
+		for _, session := range h.Sessions {
+			if session.Name == name {
+				entry.Data["mode"] = session.CurrentMode()
+				return nil
+			}
+		}
+	}
+	entry.Data["mode"] = h.allModes()
+	return nil
+}
+
+// allModes returns the mode shared by all sessions or the name=mode pairs of
+// the sessions if they differ
+func (h *ModeHook) allModes() string {
+	first := h.Sessions[0].CurrentMode()
+	same := true
+	modes := make([]string, 0, len(h.Sessions))
+	for _, session := range h.Sessions {
+		mode := session.CurrentMode()
+		same = same && mode == first
+		modes = append(modes, fmt.Sprintf("%s=%s", session.Name, mode))
+	}
+	if same {
+		return first
+	}
+	return strings.Join(modes, ",")
+}
@@ -0,0 +1,48 @@
+package pages
+
+import (
+	log "github.com/sirupsen/logrus"
+	"testing"
+)
+
+func TestModeHookUsesAccountOfEntry(t *testing.T) {
+	hook := &ModeHook{Sessions: []*Session{{Name: "main", Mode: REAL}, {Name: "test", Mode: DEMO}}}
+
+	entry := log.WithField("account", "test")
+	if err := hook.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Data["mode"] != DEMO {
+		t.Errorf("mode of test is %v, want %s", entry.Data["mode"], DEMO)
+	}
+
+	// a line of no account gets the modes of all accounts
+	entry = log.WithField("id", 1)
+	if err := hook.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Data["mode"] != "main=real,test=demo" {
+		t.Errorf("mode of a line of no account is %v", entry.Data["mode"])
+	}
+
+	// the shared mode is logged as it is
+	hook.Sessions[0].Mode = DEMO
+	entry = log.WithField("id", 1)
+	if err := hook.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Data["mode"] != DEMO {
+		t.Errorf("shared mode is logged as %v", entry.Data["mode"])
+	}
+}
+
+func TestModeHookSingleSession(t *testing.T) {
+	hook := &ModeHook{Sessions: []*Session{{Name: "main", Mode: DEMO}}}
+	entry := log.WithField("id", 1)
+	if err := hook.Fire(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Data["mode"] != DEMO {
+		t.Errorf("mode is %v, want %s", entry.Data["mode"], DEMO)
+	}
+}
@@ -0,0 +1,334 @@
+package pages
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/tebeka/selenium"
+	slog "github.com/tebeka/selenium/log"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NetworkSource builds the positions of the JSON which the web app receives from
+// its backend. Chrome writes the XHR responses and the WebSocket frames into the
+// performance log when the network logging is enabled in the capabilities.
+type NetworkSource struct {
+	Driver selenium.WebDriver
+	// HubURL is used to read the bodies of the XHR responses through DevTools
+	HubURL string
+	// Body returns the body of a response, it's set to replay recorded logs
+	Body func(requestID string) (string, error)
+
+	mu        sync.Mutex
+	positions map[string]*Position
+	updated   time.Time
+	// requests are the XHR requests with JSON responses which are still loading
+	requests map[string]string
+}
+
+// devToolsEvent is an entry of the performance log
+type devToolsEvent struct {
+	Message struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	} `json:"message"`
+}
+
+// networkPosition is a position in the JSON of the web app, the fields are
+// named as the columns of the positions table
+type networkPosition struct {
+	Code         string   `json:"code"`
+	Name         string   `json:"name"`
+	Quantity     float64  `json:"quantity"`
+	Direction    string   `json:"direction"`
+	AveragePrice float64  `json:"averagePrice"`
+	CurrentPrice float64  `json:"currentPrice"`
+	LimitPrice   *float64 `json:"limitPrice"`
+	StopPrice    *float64 `json:"stopPrice"`
+	TrailingStop *float64 `json:"trailingStop"`
+	Margin       float64  `json:"margin"`
+	Created      string   `json:"created"`
+	PPL          float64  `json:"ppl"`
+}
+
+// NewNetworkSource creates a source reading the performance log of the driver
+func NewNetworkSource(driver selenium.WebDriver, hubURL string) *NetworkSource {
+	source := &NetworkSource{Driver: driver, HubURL: hubURL}
+	source.Body = source.devToolsBody
+	return source
+}
+
+// Poll processes the entries written to the performance log since the last call
+func (s *NetworkSource) Poll() error {
+	messages, err := s.Driver.Log(slog.Performance)
+	if err != nil {
+		return err
+	}
+	return s.Process(messages)
+}
+
+// Process updates the positions with the entries of the performance log
+func (s *NetworkSource) Process(messages []slog.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requests == nil {
+		s.requests = make(map[string]string, 0)
+	}
+
+	for _, message := range messages {
+		event := &devToolsEvent{}
+		if err := json.Unmarshal([]byte(message.Message), event); err != nil {
+			log.Debug("Performance log entry is skipped: ", err)
+			continue
+		}
+		params := event.Message.Params
+		switch event.Message.Method {
+		case "Network.responseReceived":
+			s.responseReceived(params)
+		case "Network.loadingFinished":
+			s.loadingFinished(params, message.Timestamp)
+		case "Network.webSocketFrameReceived":
+			var frame struct {
+				Response struct {
+					PayloadData string `json:"payloadData"`
+				} `json:"response"`
+			}
+			if err := json.Unmarshal(params, &frame); err == nil {
+				s.feed(frame.Response.PayloadData, message.Timestamp)
+			}
+		}
+	}
+	return nil
+}
+
+// Positions returns the last known positions mapped by their guids and the time of
+// their update, ok is false until the web app has sent the whole list
+func (s *NetworkSource) Positions() (positions map[string]*Position, updated time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.positions == nil {
+		return nil, time.Time{}, false
+	}
+	positions = make(map[string]*Position, len(s.positions))
+	for guid, position := range s.positions {
+		copied := *position
+		positions[guid] = &copied
+	}
+	return positions, s.updated, true
+}
+
+func (s *NetworkSource) responseReceived(params json.RawMessage) {
+	var data struct {
+		RequestID string `json:"requestId"`
+		Type      string `json:"type"`
+		Response  struct {
+			URL      string `json:"url"`
+			MimeType string `json:"mimeType"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(params, &data); err != nil {
+		return
+	}
+	if (data.Type == "XHR" || data.Type == "Fetch") && strings.Contains(data.Response.MimeType, "json") {
+		s.requests[data.RequestID] = data.Response.URL
+	}
+}
+
+func (s *NetworkSource) loadingFinished(params json.RawMessage, at time.Time) {
+	var data struct {
+		RequestID string `json:"requestId"`
+	}
+	if err := json.Unmarshal(params, &data); err != nil {
+		return
+	}
+	url, ok := s.requests[data.RequestID]
+	if !ok || s.Body == nil {
+		return
+	}
+	delete(s.requests, data.RequestID)
+	body, err := s.Body(data.RequestID)
+	if err != nil {
+		log.Debugf("Body of %s is not read: %s", url, err)
+		return
+	}
+	s.feed(body, at)
+}
+
+// feed applies a payload, a list of the positions replaces the known ones and
+// a single position is updated or removed if its quantity is zero
+func (s *NetworkSource) feed(payload string, at time.Time) {
+	// socket.io frames start with the code of the packet
+	payload = strings.TrimLeft(payload, "0123456789")
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	// the ids are kept as they are, a float64 breaks the long ones
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return
+	}
+	s.walk(data, at)
+}
+
+func (s *NetworkSource) walk(data interface{}, at time.Time) {
+	switch value := data.(type) {
+	case []interface{}:
+		for _, item := range value {
+			s.walk(item, at)
+		}
+	case map[string]interface{}:
+		if list, ok := value["positions"].([]interface{}); ok {
+			positions := make(map[string]*Position, len(list))
+			for _, item := range list {
+				if guid, position := decodePosition(item); guid != "" {
+					positions[guid] = position
+				}
+			}
+			s.positions = positions
+			s.updated = at
+			return
+		}
+		if _, ok := value["positionId"]; ok {
+			guid, position := decodePosition(value)
+			if guid == "" || s.positions == nil {
+				return
+			}
+			if position.Quantity == 0 {
+				delete(s.positions, guid)
+			} else {
+				s.positions[guid] = position
+			}
+			s.updated = at
+			return
+		}
+		for _, item := range value {
+			s.walk(item, at)
+		}
+	}
+}
+
+// decodePosition makes a position of a JSON object, guid is empty if it's not a position.
+// The positionId is expected to be the guid of the item-<guid> row, AccountPage checks
+// it against the table before it trusts the network positions.
+func decodePosition(data interface{}) (string, *Position) {
+	value, ok := data.(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	guid := ""
+	switch id := value["positionId"].(type) {
+	case string:
+		guid = id
+	case json.Number:
+		guid = id.String()
+	}
+	raw, err := json.Marshal(value)
+	if err != nil || guid == "" {
+		return "", nil
+	}
+	np := &networkPosition{}
+	if err := json.Unmarshal(raw, np); err != nil {
+		return "", nil
+	}
+
+	position := &Position{}
+	position.Instrument = np.Code
+	if np.Name != "" {
+		position.Instrument = np.Name
+	}
+	position.Direction = strings.ToLower(np.Direction)
+	if position.Direction == "" {
+		// the quantity of a short position is negative
+		position.Direction = BUY
+		if np.Quantity < 0 {
+			position.Direction = SELL
+		}
+	}
+	if np.Quantity < 0 {
+		np.Quantity = -np.Quantity
+	}
+	position.Quantity = int(np.Quantity)
+	position.Price = np.AveragePrice
+	position.CurrentPrice = np.CurrentPrice
+	position.TakeProfit = optionalFloat(np.LimitPrice)
+	position.StopLoss = optionalFloat(np.StopPrice)
+	position.TrailingStop = optionalFloat(np.TrailingStop)
+	position.Margin = np.Margin
+	position.DateCreated = np.Created
+	position.Result = np.PPL
+	return guid, position
+}
+
+func optionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return formatFloat(*value)
+}
+
+// devToolsBody reads the body of a response through the DevTools endpoint of chromedriver
+func (s *NetworkSource) devToolsBody(requestID string) (string, error) {
+	command := map[string]interface{}{
+		"cmd":    "Network.getResponseBody",
+		"params": map[string]string{"requestId": requestID},
+	}
+	data, _ := json.Marshal(command)
+	url := fmt.Sprintf("%s/session/%s/goog/cdp/execute", strings.TrimRight(s.HubURL, "/"), s.Driver.SessionID())
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Value struct {
+			Body          string `json:"body"`
+			Base64Encoded bool   `json:"base64Encoded"`
+		} `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(devToolsFailed, resp.Status)
+	}
+	if !reply.Value.Base64Encoded {
+		return reply.Value.Body, nil
+	}
+	body, err := base64.StdEncoding.DecodeString(reply.Value.Body)
+	return string(body), err
+}
+
+// recordedEntry is an entry of the performance log as chromedriver sends it
+type recordedEntry struct {
+	Timestamp int64  `json:"timestamp"`
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+}
+
+// ReadNetworkLog reads a recorded performance log, a JSON array of its entries
+func ReadNetworkLog(r io.Reader) ([]slog.Message, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]recordedEntry, 0)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	messages := make([]slog.Message, 0, len(entries))
+	for _, entry := range entries {
+		messages = append(messages, slog.Message{
+			Timestamp: time.Unix(0, entry.Timestamp*int64(time.Millisecond)),
+			Level:     slog.Level(entry.Level),
+			Message:   entry.Message,
+		})
+	}
+	return messages, nil
+}
@@ -0,0 +1,132 @@
+package pages
+
+import (
+	"encoding/json"
+	slog "github.com/tebeka/selenium/log"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// replaySource returns a source whose bodies are read from the recorded ones
+func replaySource(t *testing.T) (*NetworkSource, []slog.Message) {
+	t.Helper()
+	f, err := os.Open("testdata/performance_log.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	messages, err := ReadNetworkLog(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile("testdata/performance_bodies.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bodies := make(map[string]string, 0)
+	if err := json.Unmarshal(data, &bodies); err != nil {
+		t.Fatal(err)
+	}
+	source := &NetworkSource{Body: func(requestID string) (string, error) {
+		return bodies[requestID], nil
+	}}
+	return source, messages
+}
+
+func checkPosition(t *testing.T, positions map[string]*Position, guid string, want Position) {
+	t.Helper()
+	got, ok := positions[guid]
+	if !ok {
+		t.Fatalf("position %s is not found", guid)
+	}
+	if got.Instrument != want.Instrument || got.Direction != want.Direction || got.Quantity != want.Quantity ||
+		got.Price != want.Price || got.CurrentPrice != want.CurrentPrice || got.TakeProfit != want.TakeProfit ||
+		got.StopLoss != want.StopLoss || got.TrailingStop != want.TrailingStop {
+		t.Errorf("position %s is %+v, want %+v", guid, *got, want)
+	}
+}
+
+func TestNetworkSourceReadsListResponse(t *testing.T) {
+	source, messages := replaySource(t)
+	// the request, the responses and the finished loading of the list
+	if err := source.Process(messages[:4]); err != nil {
+		t.Fatal(err)
+	}
+	positions, updated, ok := source.Positions()
+	if !ok {
+		t.Fatal("positions list is not read")
+	}
+	if !updated.Equal(messages[3].Timestamp) {
+		t.Errorf("updated at %s, want %s", updated, messages[3].Timestamp)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("%d positions, want 2", len(positions))
+	}
+	checkPosition(t, positions, "2812647", Position{BasePosition: BasePosition{
+		Instrument: "Apple", Direction: BUY, Quantity: 10, Price: 171.25, CurrentPrice: 173.1, TakeProfit: "180.5",
+	}})
+	// a numeric id and a negative quantity of a short position
+	checkPosition(t, positions, "2812650", Position{BasePosition: BasePosition{
+		Instrument: "Tesla", Direction: SELL, Quantity: 3, Price: 705.4, CurrentPrice: 699.9, StopLoss: "730",
+	}, TrailingStop: "15"})
+}
+
+func TestNetworkSourceAppliesSocketUpdates(t *testing.T) {
+	source, messages := replaySource(t)
+	if err := source.Process(messages); err != nil {
+		t.Fatal(err)
+	}
+	positions, updated, ok := source.Positions()
+	if !ok {
+		t.Fatal("positions list is not read")
+	}
+	last := messages[len(messages)-1].Timestamp
+	if !updated.Equal(last) {
+		t.Errorf("updated at %s, want %s", updated, last)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("%d positions, want 2", len(positions))
+	}
+	checkPosition(t, positions, "2812647", Position{BasePosition: BasePosition{
+		Instrument: "Apple", Direction: BUY, Quantity: 10, Price: 171.25, CurrentPrice: 174.02, TakeProfit: "180.5",
+	}})
+	checkPosition(t, positions, "2812655", Position{BasePosition: BasePosition{
+		Instrument: "EUR/USD", Direction: BUY, Quantity: 1000, Price: 1.0852, CurrentPrice: 1.0849,
+	}})
+	// a zero quantity closes the position
+	if _, ok := positions["2812650"]; ok {
+		t.Error("closed position 2812650 is kept")
+	}
+}
+
+func TestNetworkSourceWaitsForList(t *testing.T) {
+	source, messages := replaySource(t)
+	// the socket updates come before any list
+	if err := source.Process(messages[4:]); err != nil {
+		t.Fatal(err)
+	}
+	if _, updated, ok := source.Positions(); ok || !updated.Equal(time.Time{}) {
+		t.Fatal("positions are known without a list")
+	}
+}
+
+func TestVerifyNetwork(t *testing.T) {
+	network := map[string]*Position{"2812647": {}, "2812650": {}}
+	page := &AccountPage{Network: &NetworkSource{}}
+	page.verifyNetwork(network, map[string]*Position{})
+	if page.networkVerified || page.Network == nil {
+		t.Fatal("an empty table is not a proof")
+	}
+	page.verifyNetwork(network, map[string]*Position{"2812647": {}, "2812660": {}})
+	if !page.networkVerified {
+		t.Fatal("matched ids are not verified")
+	}
+
+	page = &AccountPage{Network: &NetworkSource{}}
+	page.verifyNetwork(network, map[string]*Position{"ab12cd": {}})
+	if page.networkVerified || page.Network != nil {
+		t.Fatal("network source with other ids is kept")
+	}
+}
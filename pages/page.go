@@ -4,10 +4,10 @@ import "github.com/tebeka/selenium"
 
 // Page struct
 type Page struct {
-	Driver selenium.WebDriver
+	Driver Driver
 }
 
-func (s *Page) driver() selenium.WebDriver {
+func (s *Page) driver() Driver {
 	return s.Driver
 }
 
@@ -110,7 +110,7 @@ func (s *Page) FindElementsByCSS(locator string) []selenium.WebElement {
 // MouseHoverToElement hovers a mouse over
 func (s *Page) MouseHoverToElement(locator string) selenium.WebElement {
 	element, _ := s.Driver.FindElement(selenium.ByCSSSelector, locator)
-	element.MoveTo(0, 0)
+	s.Driver.MoveTo(element)
 	return element
 }
 
@@ -125,8 +125,8 @@ func (s *Page) FindCSSValue(by, name string) string {
 }
 
 // Displayed function
-func (s *Page) Displayed(by, elementName string) func(selenium.WebDriver) (bool, error) {
-	return func(wd selenium.WebDriver) (bool, error) {
+func (s *Page) Displayed(by, elementName string) Condition {
+	return func(wd Driver) (bool, error) {
 		elem, err := wd.FindElement(by, elementName)
 		if err != nil {
 			return false, nil
@@ -143,8 +143,8 @@ func (s *Page) Displayed(by, elementName string) func(selenium.WebDriver) (bool,
 }
 
 // NotFound function
-func (s *Page) NotFound(by, elementName string) func(selenium.WebDriver) (bool, error) {
-	return func(wd selenium.WebDriver) (bool, error) {
+func (s *Page) NotFound(by, elementName string) Condition {
+	return func(wd Driver) (bool, error) {
 		elem, err := wd.FindElement(by, elementName)
 		/*if elem != nil {
 			return false, nil
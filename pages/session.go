@@ -0,0 +1,307 @@
+package pages
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tebeka/selenium"
+)
+
+const (
+	loginMinBackoff = time.Second * 5
+	loginMaxBackoff = time.Minute * 5
+)
+
+// Session keeps the account page logged in with the configured credentials
+type Session struct {
+	// Name of the account
+	Name     string
+	Page     Page
+	URL      string
+	Login    string
+	Password string
+	// Mode is the initial mode, see CurrentMode
+	Mode string
+	// TOTPSecret is the base32 secret of the two-factor authentication
+	TOTPSecret string
+	// AccountURL is opened after cookies are restored, URL is used if empty.
+	// A %s verb is replaced with the subdomain of the mode (demo or live).
+	AccountURL string
+	// Cookies keeps the cookies between restarts, nil disables it
+	Cookies *CookieJar
+
+	mu           sync.Mutex
+	mode         string
+	executor     *Executor
+	loggedIn     bool
+	lastErr      error
+	attempts     int
+	since        time.Time
+	retrying     bool
+	cookiesTried bool
+}
+
+// SessionStatus describes the current login state
+type SessionStatus struct {
+	Mode     string    `json:"mode"`
+	LoggedIn bool      `json:"logged_in"`
+	Code     string    `json:"code,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Attempts int       `json:"attempts"`
+	Since    time.Time `json:"since"`
+}
+
+// Start logs in through the executor in the background, retrying with backoff
+func (s *Session) Start(executor *Executor) {
+	s.mu.Lock()
+	s.executor = executor
+	s.since = time.Now()
+	if s.mode == "" {
+		s.mode = s.Mode
+	}
+	if s.mode == "" {
+		s.mode = REAL
+	}
+	s.mu.Unlock()
+	s.retry()
+}
+
+// logger returns the log entry of the account, the mode is added to it by ModeHook
+func (s *Session) logger() *log.Entry {
+	return log.WithField("account", s.Name)
+}
+
+// CurrentMode returns the mode the session is logged in (demo or real)
+func (s *Session) CurrentMode() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mode == "" {
+		return s.Mode
+	}
+	return s.mode
+}
+
+// SwitchMode logs out and logs in again in the given mode
+func (s *Session) SwitchMode(mode string) error {
+	if !ValidMode(mode) {
+		return fmt.Errorf(unacceptableValue, mode)
+	}
+	s.mu.Lock()
+	executor := s.executor
+	s.mu.Unlock()
+	if executor == nil {
+		return ErrNotLoggedIn
+	}
+	return executor.Do(func() error {
+		if s.CurrentMode() == mode {
+			return nil
+		}
+		s.logger().Warnf("Switching mode to %s", mode)
+		s.mu.Lock()
+		s.mode = mode
+		s.loggedIn = false
+		s.mu.Unlock()
+		s.Page.Driver.DeleteAllCookies()
+		return s.Restore()
+	})
+}
+
+// Status returns the current login state
+func (s *Session) Status() SessionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := SessionStatus{Mode: s.mode, LoggedIn: s.loggedIn, Attempts: s.attempts, Since: s.since}
+	if s.lastErr != nil {
+		status.Error = s.lastErr.Error()
+		if loginErr, ok := s.lastErr.(*LoginError); ok {
+			status.Code = loginErr.Code
+		}
+	}
+	return status
+}
+
+// Check returns ErrNotLoggedIn or ErrSessionExpired if the account is not
+// available
+func (s *Session) Check() error {
+	s.mu.Lock()
+	loggedIn := s.loggedIn
+	s.mu.Unlock()
+	if !loggedIn {
+		return ErrNotLoggedIn
+	}
+	account := &AccountPage{Page: s.Page}
+	return account.checkSessionExpired()
+}
+
+// Relogin opens the login page and logs in again.
+// It must be called by the executor which owns the browser.
+func (s *Session) Relogin() error {
+	// saved cookies are tried once, on the first login after a start
+	if s.Cookies != nil && !s.cookiesTried {
+		s.cookiesTried = true
+		if s.restoreCookies() {
+			isAllProperties = false
+			s.setResult(nil)
+			return nil
+		}
+	}
+
+	s.logger().Info("Logging in")
+	err := s.Page.Driver.Get(s.URL)
+	if err == nil {
+		home := &HomePage{Page: s.Page, Mode: s.CurrentMode(), TOTPSecret: s.TOTPSecret}
+		_, err = home.LoginToAccount(s.Login, s.Password)
+	}
+	if err == nil {
+		// the page is reloaded, so the table columns have to be switched on again
+		isAllProperties = false
+		s.logger().Info("Session is restored")
+		s.saveCookies()
+	}
+	s.setResult(err)
+	return err
+}
+
+// restoreCookies loads saved cookies and checks if they are still logged in
+func (s *Session) restoreCookies() bool {
+	cookies, err := s.Cookies.Load(s.CurrentMode())
+	if err != nil {
+		s.logger().Debug("Cookies are not restored: ", err)
+		return false
+	}
+	// cookies can only be added to the page of their domain
+	if err := s.Page.Driver.Get(s.URL); err != nil {
+		s.logger().Debug("Cookies are not restored: ", err)
+		return false
+	}
+	for i := range cookies {
+		if err := s.Page.Driver.AddCookie(&cookies[i]); err != nil {
+			s.logger().Debugf("Cookie %s is not added: %s", cookies[i].Name, err)
+		}
+	}
+	if err := s.Page.Driver.Get(s.accountURL()); err != nil {
+		s.logger().Debug("Cookies are not restored: ", err)
+		return false
+	}
+	err = s.Page.Driver.WaitWithTimeout(s.Page.Displayed(selenium.ByCSSSelector, domPaths["nav_logo"]), time.Second*10)
+	if err != nil {
+		s.logger().Info("Saved cookies are not logged in, falling back to the login form")
+		s.Page.Driver.DeleteAllCookies()
+		return false
+	}
+	s.logger().Info("Session is restored from the saved cookies")
+	return true
+}
+
+func (s *Session) accountURL() string {
+	if s.AccountURL == "" {
+		return s.URL
+	}
+	if strings.Contains(s.AccountURL, "%s") {
+		return fmt.Sprintf(s.AccountURL, accountHosts[s.CurrentMode()])
+	}
+	return s.AccountURL
+}
+
+// saveCookies writes cookies of the logged in session
+func (s *Session) saveCookies() {
+	if s.Cookies == nil {
+		return
+	}
+	cookies, err := s.Page.Driver.GetCookies()
+	if err == nil {
+		err = s.Cookies.Save(s.CurrentMode(), cookies)
+	}
+	if err != nil {
+		s.logger().Warn("Cookies are not saved: ", err)
+		return
+	}
+	s.logger().Debugf("Saved %d cookies", len(cookies))
+}
+
+// Ensure logs in again if the account has been logged out.
+// It must be called by the executor which owns the browser.
+func (s *Session) Ensure() error {
+	err := s.Check()
+	if err != ErrSessionExpired {
+		return err
+	}
+	s.logger().Warn("Session has expired, logging in again")
+	return s.Restore()
+}
+
+// Restore logs in again and keeps retrying in the background on failure.
+// It must be called by the executor which owns the browser.
+func (s *Session) Restore() error {
+	err := s.Relogin()
+	if err != nil {
+		s.retry()
+	}
+	return err
+}
+
+func (s *Session) setResult(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.loggedIn != (err == nil) {
+		s.since = time.Now()
+	}
+	s.loggedIn = err == nil
+	s.lastErr = err
+	if err == nil {
+		s.attempts = 0
+	} else {
+		s.attempts++
+	}
+}
+
+// retry starts the background login loop unless it's already running
+func (s *Session) retry() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retrying || s.executor == nil {
+		return
+	}
+	s.retrying = true
+	go s.loginLoop()
+}
+
+func (s *Session) loginLoop() {
+	defer func() {
+		s.mu.Lock()
+		s.retrying = false
+		s.mu.Unlock()
+	}()
+
+	s.mu.Lock()
+	executor := s.executor
+	s.mu.Unlock()
+
+	backoff := loginMinBackoff
+	for {
+		err := executor.Do(func() error {
+			if s.Check() == nil {
+				return nil
+			}
+			return s.Relogin()
+		})
+		if err == nil || err == errExecutorStopped {
+			return
+		}
+		if loginErr, ok := err.(*LoginError); ok && !loginErr.Retry {
+			s.logger().WithField("code", loginErr.Code).Error("Login is not retried: ", err)
+			return
+		}
+		s.logger().Warnf("Login failed: %s, next attempt in %s", err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > loginMaxBackoff {
+			backoff = loginMaxBackoff
+		}
+	}
+}
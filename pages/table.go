@@ -0,0 +1,114 @@
+package pages
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// positionCells are the cells of the positions table, all of them are shown after switchAll
+var positionCells = []string{"cell_name", "cell_qty", "cell_dir", "cell_avgprice", "cell_curprice",
+	"cell_tp", "cell_sl", "cell_ts", "cell_margin", "cell_created", "result"}
+
+// tableScript reads the texts of the cells of all rows in a single call to the browser,
+// the result is serialized to JSON to keep the types simple
+const tableScript = `
+var rows = document.querySelectorAll(arguments[0]);
+var cells = arguments[1];
+var result = [];
+for (var i = 0; i < rows.length; i++) {
+	var row = {id: rows[i].id, cells: {}};
+	for (var key in cells) {
+		var cell = rows[i].querySelector(cells[key]);
+		row.cells[key] = cell ? cell.textContent.trim() : "";
+	}
+	result.push(row);
+}
+return JSON.stringify(result);`
+
+// tableRow is a row of the table with the texts of its cells
+type tableRow struct {
+	ID    string            `json:"id"`
+	Cells map[string]string `json:"cells"`
+}
+
+// GUID returns the guid of the row, it's empty for the rows which aren't items
+func (r *tableRow) GUID() string {
+	if !strings.HasPrefix(r.ID, "item-") {
+		return ""
+	}
+	return strings.Replace(r.ID, "item-", "", 1)
+}
+
+// scrapeTable reads the cells of all rows matching the path in one pass
+func (p *AccountPage) scrapeTable(rowsPath string, cells []string) ([]*tableRow, error) {
+	selectors := make(map[string]string, len(cells))
+	for _, cell := range cells {
+		selectors[cell] = domPaths[cell]
+	}
+	data, err := p.Page.Driver.ExecuteScript(tableScript, []interface{}{domPaths[rowsPath], selectors})
+	if err != nil {
+		return nil, err
+	}
+	str, ok := data.(string)
+	if !ok {
+		return nil, fmt.Errorf(unexpectedScriptResult, data)
+	}
+	rows := make([]*tableRow, 0)
+	err = json.Unmarshal([]byte(str), &rows)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// checkEmptyTable returns an error unless the table is rendered and shows the
+// placeholder of no data, a table which isn't loaded yet has no rows too
+func (p *AccountPage) checkEmptyTable(tablePath string) error {
+	if p.Page.FindElementByCSS(domPaths[tablePath]) == nil {
+		return fmt.Errorf(cssError, domPaths[tablePath])
+	}
+	placeholder := p.Page.FindElementByCSS(domPaths["dt_no_data"])
+	if placeholder == nil {
+		return fmt.Errorf(tableNotRendered, tablePath)
+	}
+	if shown, err := placeholder.IsDisplayed(); err != nil || !shown {
+		return fmt.Errorf(tableNotRendered, tablePath)
+	}
+	return nil
+}
+
+// parsePosition makes a position of the texts of the cells
+func parsePosition(cells map[string]string) *Position {
+	position := &Position{}
+	position.Instrument = cells["cell_name"]
+	position.Direction = strings.ToLower(cells["cell_dir"])
+	position.TakeProfit = cells["cell_tp"]
+	position.StopLoss = cells["cell_sl"]
+	position.TrailingStop = cells["cell_ts"]
+	position.DateCreated = cells["cell_created"]
+	position.Result = parseNumber(cells["result"])
+	position.Price = parseNumber(cells["cell_avgprice"])
+	position.CurrentPrice = parseNumber(cells["cell_curprice"])
+	position.Margin = parseNumber(cells["cell_margin"])
+	if qty, err := strconv.Atoi(cleanNumber(cells["cell_qty"])); err == nil {
+		position.Quantity = qty
+	}
+	return position
+}
+
+// parseNumber parses a number of a cell, it's 0 if the cell isn't a number
+func parseNumber(str string) float64 {
+	value, _ := strconv.ParseFloat(cleanNumber(str), 64)
+	return value
+}
+
+// numberSeparators are the thousands separators of the platform, it uses the
+// non-breaking spaces as well as the plain ones
+var numberSeparators = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", ",", "")
+
+// cleanNumber removes the thousands separators of the number
+func cleanNumber(str string) string {
+	return numberSeparators.Replace(strings.TrimSpace(str))
+}
@@ -0,0 +1,53 @@
+package pages
+
+import (
+	"testing"
+)
+
+func TestCheckEmptyTable(t *testing.T) {
+	driver := testBrowser(t)
+	page := &AccountPage{Page: Page{Driver: driver}}
+
+	openFixture(t, driver, "positions_empty.html")
+	if err := page.checkEmptyTable("positions_table"); err != nil {
+		t.Errorf("empty table: %s", err)
+	}
+	// there is no table on the page, it isn't rendered yet
+	openFixture(t, driver, "elements.html")
+	if err := page.checkEmptyTable("positions_table"); err == nil {
+		t.Error("missing table is taken as empty")
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	position := parsePosition(map[string]string{
+		"cell_name":     "Amazon",
+		"cell_dir":      "Buy",
+		"cell_qty":      "1\u00a0200",
+		"cell_avgprice": " 3\u202f105.25 ",
+		"cell_curprice": "3,110.5",
+		"cell_margin":   "1 000",
+		"result":        "-12.5",
+	})
+	if position.Quantity != 1200 {
+		t.Errorf("quantity is %d, want 1200", position.Quantity)
+	}
+	if position.Price != 3105.25 {
+		t.Errorf("price is %v, want 3105.25", position.Price)
+	}
+	if position.CurrentPrice != 3110.5 {
+		t.Errorf("current price is %v, want 3110.5", position.CurrentPrice)
+	}
+	if position.Margin != 1000 || position.Result != -12.5 {
+		t.Errorf("margin is %v and result is %v", position.Margin, position.Result)
+	}
+	if position.Direction != "buy" {
+		t.Errorf("direction is %s", position.Direction)
+	}
+}
+
+func TestParseNumberNotANumber(t *testing.T) {
+	if value := parseNumber("-"); value != 0 {
+		t.Errorf("value of a dash is %v", value)
+	}
+}
@@ -0,0 +1,46 @@
+package pages
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	totpStep   = 30
+	totpDigits = 6
+)
+
+// TOTP generates a time-based one-time password (RFC 6238) from the base32
+// secret shown by the platform when 2FA is enabled
+func TOTP(secret string, t time.Time) (string, error) {
+	secret = strings.ToUpper(strings.Replace(secret, " ", "", -1))
+	secret = strings.TrimRight(secret, "=")
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf(totpSecretInvalid)
+	}
+	counter := uint64(t.Unix() / totpStep)
+	return hotp(key, counter), nil
+}
+
+// hotp generates an HMAC-based one-time password (RFC 4226)
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, code%mod)
+}
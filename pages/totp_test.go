@@ -0,0 +1,55 @@
+package pages
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 of the ASCII key "12345678901234567890" of RFC 4226 and RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226, appendix D
+	codes := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, want := range codes {
+		if got := hotp([]byte("12345678901234567890"), uint64(counter)); got != want {
+			t.Errorf("counter %d: got %s, want %s", counter, got, want)
+		}
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238, appendix B (SHA1), the last 6 of the 8 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := TOTP(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%d: got %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPSecretFormat(t *testing.T) {
+	at := time.Unix(59, 0)
+	// the platform shows the secret in groups of lower case letters
+	got, err := TOTP("gezd gnbv gy3t qojq gezd gnbv gy3t qojq", at)
+	if err != nil || got != "287082" {
+		t.Errorf("got %s, %v", got, err)
+	}
+	if _, err := TOTP("not base32!", at); err == nil {
+		t.Error("invalid secret is accepted")
+	}
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"strconv"
+	"strings"
+)
+
+// Names of the supported drivers
+const (
+	MySQL    = "mysql"
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+// dialect describes the differences of the databases
+type dialect struct {
+	// driver is the name of the database/sql driver
+	driver string
+	// numbered placeholders ($1) are used instead of ?
+	numbered bool
+	// returning reads ids of the inserted rows with RETURNING instead of LastInsertId
+	returning bool
+	// now is the current UTC time
+	now string
+	// maxConns limits the open connections
+	maxConns int
+}
+
+// timeFormat is the format of the time parameters
+const timeFormat = "2006-01-02 15:04:05"
+
+var dialects = map[string]*dialect{
+	MySQL:    {driver: "mysql", now: "UTC_TIMESTAMP()", maxConns: 10},
+	Postgres: {driver: "postgres", numbered: true, returning: true, now: "(NOW() AT TIME ZONE 'UTC')", maxConns: 10},
+	// sqlite allows only one writer, so the connection is shared
+	SQLite: {driver: "sqlite3", now: "datetime('now')", maxConns: 1},
+}
+
+// DB is a database of one of the supported drivers
+type DB struct {
+	*sql.DB
+	name    string
+	dialect *dialect
+}
+
+// Open connects to the database of the driver, mysql is used if it's empty
+func Open(driver, dsn string) (*DB, error) {
+	if driver == "" {
+		driver = MySQL
+	}
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown database driver: '%s', use %s, %s or %s", driver, MySQL, Postgres, SQLite)
+	}
+	if driver == MySQL {
+		dsn += "&charset=utf8"
+		dsn += "&interpolateParams=true"
+		dsn += "&parseTime=true"
+	}
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(d.maxConns)
+	return &DB{DB: db, name: driver, dialect: d}, nil
+}
+
+// Driver returns the name of the driver
+func (db *DB) Driver() string {
+	return db.name
+}
+
+// Rebind replaces ? placeholders with the ones of the driver
+func (db *DB) Rebind(query string) string {
+	if !db.dialect.numbered {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Items returns the positions of the account
+func (db *DB) Items(account string) ItemRepository {
+	return &items{db: db, account: account}
+}
+
+// Orders returns the pending orders of the account
+func (db *DB) Orders(account string) OrderRepository {
+	return &orders{db: db, account: account}
+}
+
+func (db *DB) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.Query(db.Rebind(query), args...)
+}
+
+func (db *DB) queryRow(query string, args ...interface{}) *sql.Row {
+	return db.QueryRow(db.Rebind(query), args...)
+}
+
+func (db *DB) exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.Exec(db.Rebind(query), args...)
+}
+
+// insert runs the insert and returns the value of the key column of the new row
+func (db *DB) insert(query, key string, args ...interface{}) (int64, error) {
+	if db.dialect.returning {
+		var id int64
+		err := db.QueryRow(db.Rebind(query+" RETURNING "+key), args...).Scan(&id)
+		return id, err
+	}
+	result, err := db.exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
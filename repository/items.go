@@ -0,0 +1,278 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"trading/pages"
+)
+
+// maxIntentError is the size of the intent_error column
+const maxIntentError = 255
+
+// items keeps the positions of an account in the items table
+type items struct {
+	db      *DB
+	account string
+}
+
+func (r *items) FindGUID(id int) (string, error) {
+	rows, err := r.db.query("SELECT item_key FROM items WHERE item_id = ? AND account = ? AND status = ? AND intent = ?", id, r.account, ItemOpen, IntentConfirmed)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var guid string
+	for rows.Next() {
+		err = rows.Scan(&guid)
+		if err != nil {
+			return "", err
+		}
+	}
+	return guid, rows.Err()
+}
+
+func (r *items) Find(id int) (*pages.DbItem, error) {
+	rows, err := r.db.query("SELECT item_id, instrument, item_key, direction, qty, price FROM items WHERE item_id = ? AND account = ? AND status = ? AND intent = ?", id, r.account, ItemOpen, IntentConfirmed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	item := pages.DbItem{}
+	for rows.Next() {
+		var price sql.NullFloat64
+		err = rows.Scan(&item.ID, &item.Instrument, &item.GUID, &item.Dir, &item.Qty, &price)
+		if err != nil {
+			return nil, err
+		}
+		item.Price = price.Float64
+	}
+	return &item, rows.Err()
+}
+
+func (r *items) Opened() (map[int]string, error) {
+	rows, err := r.db.query("SELECT item_id, item_key FROM items WHERE account = ? AND status = ? AND intent = ?", r.account, ItemOpen, IntentConfirmed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	guids := make(map[int]string, 0)
+	for rows.Next() {
+		var guid string
+		var id int
+		err = rows.Scan(&id, &guid)
+		if err != nil {
+			return nil, err
+		}
+		guids[id] = guid
+	}
+	return guids, rows.Err()
+}
+
+func (r *items) ListOpen() ([]*pages.DbItem, error) {
+	rows, err := r.db.query(
+		"SELECT item_id, instrument, item_key, direction, qty, price FROM items WHERE account = ? AND status = ? AND intent = ? ORDER BY item_id",
+		r.account, ItemOpen, IntentConfirmed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := make([]*pages.DbItem, 0)
+	for rows.Next() {
+		item := &pages.DbItem{}
+		var price sql.NullFloat64
+		err = rows.Scan(&item.ID, &item.Instrument, &item.GUID, &item.Dir, &item.Qty, &price)
+		if err != nil {
+			return nil, err
+		}
+		item.Price = price.Float64
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *items) Adopt(guid string, position *pages.Position) (int64, error) {
+	id, err := r.db.insert(
+		"INSERT INTO items (account, instrument, item_key, direction, qty, price, intent) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		"item_id", r.account, position.Instrument, guid, position.Direction, position.Quantity, position.Price, IntentConfirmed,
+	)
+	if err != nil {
+		return 0, err
+	}
+	log.Debug(fmt.Sprintf("Adopt: lastInsertedId: %d, guid: %s", id, guid))
+	return id, nil
+}
+
+func (r *items) Intend(item *pages.Item) (int64, error) {
+	id, err := r.db.insert(
+		"INSERT INTO items (account, instrument, item_key, direction, qty, price, intent) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		"item_id", r.account, item.Instrument, "", item.Direction, item.Qty, item.Price, IntentExecuting,
+	)
+	if err != nil {
+		return 0, err
+	}
+	log.Debug(fmt.Sprintf("Intent: lastInsertedId: %d", id))
+	return id, nil
+}
+
+func (r *items) SetIntent(id int64, intent, message string) error {
+	// the column keeps characters, a message cut by bytes might be invalid UTF-8
+	if runes := []rune(message); len(runes) > maxIntentError {
+		message = string(runes[:maxIntentError])
+	}
+	_, err := r.db.exec("UPDATE items SET intent = ?, intent_error = ? WHERE item_id = ? AND account = ?", intent, message, id, r.account)
+	return err
+}
+
+func (r *items) Confirm(id int64, item *pages.Item) error {
+	result, err := r.db.exec(
+		"UPDATE items SET item_key = ?, qty = ?, price = ?, intent = ?, intent_error = NULL WHERE item_id = ? AND account = ?",
+		item.Key, item.Qty, item.Price, IntentConfirmed, id, r.account,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	log.Debug(fmt.Sprintf("Confirm. RowsAffected: %d, id: %d", affected, id))
+	return nil
+}
+
+func (r *items) LastID() (int64, error) {
+	var id sql.NullInt64
+	err := r.db.queryRow("SELECT MAX(item_id) FROM items WHERE account = ?", r.account).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id.Int64, nil
+}
+
+func (r *items) Unresolved(lastID int64) ([]*Intent, error) {
+	rows, err := r.db.query(
+		"SELECT item_id, instrument, direction, qty, intent FROM items WHERE account = ? AND item_id <= ? AND intent IN (?, ?) ORDER BY item_id",
+		r.account, lastID, IntentPending, IntentExecuting,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	intents := make([]*Intent, 0)
+	for rows.Next() {
+		intent := &Intent{}
+		err = rows.Scan(&intent.ID, &intent.Instrument, &intent.Direction, &intent.Qty, &intent.Intent)
+		if err != nil {
+			return nil, err
+		}
+		intents = append(intents, intent)
+	}
+	return intents, rows.Err()
+}
+
+func (r *items) KnownGUIDs() (map[string]bool, error) {
+	rows, err := r.db.query("SELECT item_key FROM items WHERE account = ? AND item_key <> ''", r.account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	guids := make(map[string]bool, 0)
+	for rows.Next() {
+		var guid string
+		if err = rows.Scan(&guid); err != nil {
+			return nil, err
+		}
+		guids[guid] = true
+	}
+	return guids, rows.Err()
+}
+
+func (r *items) UpdateQty(item *pages.DbItem) error {
+	result, err := r.db.exec("UPDATE items SET qty = ? WHERE item_id = ? AND account = ?", item.Qty, item.ID, r.account)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	log.Debug(fmt.Sprintf("Edit. RowsAffected: %d, id: %d", affected, item.ID))
+	return nil
+}
+
+func (r *items) Close(item *pages.DbItem) error {
+	cmd := "UPDATE items SET status = ?, closed_at = " + r.db.dialect.now + ", close_price = ?, result = ?, close_reason = ? WHERE item_id = ? AND account = ?"
+	result, err := r.db.exec(cmd, ItemClosed, item.ClosePrice, item.Result, CloseAPI, item.ID, r.account)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	log.Debug(fmt.Sprintf("Close. RowsAffected: %d, id: %d", affected, item.ID))
+	return nil
+}
+
+func (r *items) CloseExternally(id int) error {
+	cmd := "UPDATE items SET status = ?, closed_at = " + r.db.dialect.now + ", close_reason = ? WHERE item_id = ? AND account = ? AND status = ?"
+	result, err := r.db.exec(cmd, ItemClosed, CloseExternal, id, r.account, ItemOpen)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	log.Debug(fmt.Sprintf("Close externally. RowsAffected: %d, id: %d", affected, id))
+	return nil
+}
+
+func (r *items) History(filter HistoryFilter) ([]*HistoryItem, error) {
+	where := []string{"account = ?", "status = ?"}
+	args := []interface{}{r.account, ItemClosed}
+	if !filter.From.IsZero() {
+		where = append(where, "closed_at >= ?")
+		args = append(args, filter.From.UTC().Format(timeFormat))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "closed_at < ?")
+		args = append(args, filter.To.UTC().Format(timeFormat))
+	}
+	if filter.Instrument != "" {
+		where = append(where, "instrument = ?")
+		args = append(args, filter.Instrument)
+	}
+
+	cmd := "SELECT item_id, instrument, direction, qty, price, status, closed_at, close_price, result, close_reason FROM items WHERE " +
+		strings.Join(where, " AND ") + " ORDER BY closed_at DESC"
+	rows, err := r.db.query(cmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]*HistoryItem, 0)
+	for rows.Next() {
+		item := &HistoryItem{}
+		var price, closePrice, result sql.NullFloat64
+		var closedAt sql.NullTime
+		var reason sql.NullString
+		err = rows.Scan(&item.ID, &item.Instrument, &item.Direction, &item.Quantity, &price,
+			&item.Status, &closedAt, &closePrice, &result, &reason)
+		if err != nil {
+			return nil, err
+		}
+		item.Price = price.Float64
+		if closedAt.Valid {
+			item.ClosedAt = &closedAt.Time
+		}
+		item.ClosePrice = closePrice.Float64
+		item.Result = result.Float64
+		item.CloseReason = reason.String
+		history = append(history, item)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,64 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"trading/migrations"
+	"trading/pages"
+	"trading/repository"
+	"unicode/utf8"
+)
+
+func openTestDB(t *testing.T) *repository.DB {
+	t.Helper()
+	db, err := repository.Open(repository.SQLite, filepath.Join(t.TempDir(), "trading.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestIntendRecordsExecuting(t *testing.T) {
+	items := openTestDB(t).Items("test")
+	id, err := items.Intend(&pages.Item{Instrument: "AAPL", Direction: pages.BUY, Qty: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	intents, err := items.Unresolved(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(intents) != 1 || intents[0].Intent != repository.IntentExecuting {
+		t.Errorf("intents: %+v", intents)
+	}
+}
+
+func TestSetIntentTruncatesByCharacters(t *testing.T) {
+	db := openTestDB(t)
+	items := db.Items("test")
+	id, err := items.Intend(&pages.Item{Instrument: "AAPL", Direction: pages.BUY, Qty: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a byte cut would split the two-byte letters
+	message := strings.Repeat("я", 300)
+	if err := items.SetIntent(id, repository.IntentFailed, message); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored string
+	if err := db.QueryRow("SELECT intent_error FROM items WHERE item_id = ?", id).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.ValidString(stored) {
+		t.Error("stored message is not valid UTF-8")
+	}
+	if n := utf8.RuneCountInString(stored); n != 255 {
+		t.Errorf("%d characters are stored, want 255", n)
+	}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"trading/pages"
+)
+
+// orders keeps the pending orders of an account in the orders table
+type orders struct {
+	db      *DB
+	account string
+}
+
+func (r *orders) FindGUID(id int) (string, error) {
+	rows, err := r.db.query("SELECT order_key FROM orders WHERE order_id = ? AND account = ?", id, r.account)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var guid string
+	for rows.Next() {
+		err = rows.Scan(&guid)
+		if err != nil {
+			return "", err
+		}
+	}
+	return guid, rows.Err()
+}
+
+func (r *orders) Pending() (map[int]string, error) {
+	rows, err := r.db.query("SELECT order_id, order_key FROM orders WHERE account = ?", r.account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	guids := make(map[int]string, 0)
+	for rows.Next() {
+		var guid string
+		var id int
+		err = rows.Scan(&id, &guid)
+		if err != nil {
+			return nil, err
+		}
+		guids[id] = guid
+	}
+	return guids, rows.Err()
+}
+
+func (r *orders) Insert(item *pages.Item) (int64, error) {
+	id, err := r.db.insert(
+		"INSERT INTO orders (account, instrument, order_key, direction, type, qty, price) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		"order_id", r.account, item.Instrument, item.Key, item.Direction, item.Type, item.Qty, item.Price,
+	)
+	if err != nil {
+		return 0, err
+	}
+	log.Debug(fmt.Sprintf("Insert order: lastInsertedId: %d", id))
+	return id, nil
+}
+
+func (r *orders) Delete(id int) error {
+	result, err := r.db.exec("DELETE FROM orders WHERE order_id = ? AND account = ?", id, r.account)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	log.Debug(fmt.Sprintf("Delete order. RowsAffected: %d, id: %d", affected, id))
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"time"
+	"trading/pages"
+)
+
+// Statuses of the items
+const (
+	ItemOpen   = "open"
+	ItemClosed = "closed"
+)
+
+// Reasons of closing the items
+const (
+	// CloseAPI is a position closed through the API
+	CloseAPI = "api"
+	// CloseExternal is a position which has vanished from the platform,
+	// it's closed by a limit or in the web interface
+	CloseExternal = "external"
+)
+
+// Intents of the items, a position is recorded as executing before it's opened
+// on the platform and it's confirmed when its guid is known. Pending is left by
+// the earlier versions which recorded it before the executing one.
+const (
+	IntentPending   = "pending"
+	IntentExecuting = "executing"
+	IntentConfirmed = "confirmed"
+	IntentFailed    = "failed"
+)
+
+// ItemRepository stores the positions opened through the API
+type ItemRepository interface {
+	// FindGUID returns the guid of an opened position, it's empty if the position is not found
+	FindGUID(id int) (string, error)
+	// Find returns an opened position, its GUID is empty if the position is not found
+	Find(id int) (*pages.DbItem, error)
+	// Opened returns the guids of the opened positions mapped by their ids
+	Opened() (map[int]string, error)
+	// ListOpen returns the opened positions
+	ListOpen() ([]*pages.DbItem, error)
+	// Adopt stores a position opened outside of the API and returns its id
+	Adopt(guid string, position *pages.Position) (int64, error)
+	// Intend records a position which is going to be opened as executing and returns its id
+	Intend(item *pages.Item) (int64, error)
+	// SetIntent changes the intent of a position, the message describes a failure
+	SetIntent(id int64, intent, message string) error
+	// Confirm stores the guid of an opened position and confirms its intent
+	Confirm(id int64, item *pages.Item) error
+	// LastID returns the id of the latest position of the account, it's 0 if there is none
+	LastID() (int64, error)
+	// Unresolved returns the positions up to lastID whose intents are pending or executing
+	Unresolved(lastID int64) ([]*Intent, error)
+	// KnownGUIDs returns the guids of all positions of the account
+	KnownGUIDs() (map[string]bool, error)
+	// UpdateQty changes the quantity of an opened position
+	UpdateQty(item *pages.DbItem) error
+	// Close keeps a closed position in the history
+	Close(item *pages.DbItem) error
+	// CloseExternally keeps a position which has vanished from the platform in the history
+	CloseExternally(id int) error
+	// History returns the closed positions
+	History(filter HistoryFilter) ([]*HistoryItem, error)
+}
+
+// OrderRepository stores the pending orders placed through the API
+type OrderRepository interface {
+	// FindGUID returns the guid of an order, it's empty if the order is not found
+	FindGUID(id int) (string, error)
+	// Pending returns the guids of the orders mapped by their ids
+	Pending() (map[int]string, error)
+	// Insert stores a new order and returns its id
+	Insert(item *pages.Item) (int64, error)
+	// Delete removes a cancelled order
+	Delete(id int) error
+}
+
+// Intent is a position which hasn't been confirmed yet
+type Intent struct {
+	ID         int64
+	Instrument string
+	Direction  string
+	Qty        int
+	Intent     string
+}
+
+// HistoryFilter selects the closed positions, zero values are not used
+type HistoryFilter struct {
+	From       time.Time
+	To         time.Time
+	Instrument string
+}
+
+// HistoryItem is a closed position
+type HistoryItem struct {
+	ID         int        `json:"id"`
+	Instrument string     `json:"instrument"`
+	Direction  string     `json:"direction"`
+	Quantity   int        `json:"quantity"`
+	Price      float64    `json:"price"`
+	Status     string     `json:"status"`
+	ClosedAt   *time.Time `json:"closed_at"`
+	ClosePrice float64    `json:"close_price"`
+	Result     float64    `json:"result"`
+	// CloseReason is api or external
+	CloseReason string `json:"close_reason"`
+}
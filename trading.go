@@ -2,40 +2,232 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
-	_ "github.com/go-sql-driver/mysql"
+	"fmt"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 	httpSwagger "github.com/swaggo/http-swagger"
-	"github.com/tebeka/selenium"
-	"github.com/tebeka/selenium/chrome"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"time"
 	"trading/api"
 	_ "trading/docs" // docs is generated by Swag CLI
+	"trading/migrations"
 	"trading/pages"
+	"trading/repository"
 )
 
+// AccountConfig struct
+type AccountConfig struct {
+	Name       string
+	Login      string
+	Password   string
+	Mode       string
+	CookieFile string
+	TOTPSecret string
+}
+
 // Config struct
 type Config struct {
 	Address    string
 	TradingURL string
+	AccountURL string
+	// Driver of the database: mysql, postgres or sqlite
+	Driver string
+	Dsn    string
+	HubURL string
+	// BrowserBackend is selenium to use the hub or cdp to start a local Chromium
+	BrowserBackend string `json:"browser_backend"`
+	// Browser is the browser and its capabilities
+	Browser BrowserConfig
+	// CookieSecret encrypts the cookie files, TRADING_COOKIE_SECRET overrides it
+	CookieSecret string
+	// AutoMigrate applies pending migrations at the start
+	AutoMigrate bool
+	// ReconcileInterval is the period of the reconciliation in seconds, 0 disables it
+	ReconcileInterval int
+	// NetworkLog reads the positions of the network data of the web app through
+	// the performance log of Chrome instead of the positions table
+	NetworkLog bool
+	// SnapshotInterval is the period of the positions snapshot refresh in seconds, 0 disables the cache
+	SnapshotInterval int
+	Accounts         []AccountConfig
+	// a single account, used if Accounts is empty
 	Login      string
 	Password   string
-	Dsn        string
-	HubURL     string
+	Mode       string
+	CookieFile string
+	TOTPSecret string
 }
 
+// account holds an independent browser session of a trading account
+type account struct {
+	lastItem   int64
+	driver     pages.Driver
+	executor   *pages.Executor
+	session    *pages.Session
+	handlers   *api.Handler
+	reconciler *api.Reconciler
+	stop       chan struct{}
+}
+
+// cookieSecretEnv is the environment variable of the cookie secret
+const cookieSecretEnv = "TRADING_COOKIE_SECRET"
+
 var (
-	config = &Config{}
-	driver selenium.WebDriver
-	page   pages.Page
+	config        = &Config{}
+	accountNameRe = regexp.MustCompile(`\A[A-Za-z0-9_-]+\z`)
 )
 
+// accounts returns the configured accounts
+func (c *Config) accounts() ([]AccountConfig, error) {
+	accounts := c.Accounts
+	if len(accounts) == 0 {
+		accounts = []AccountConfig{{
+			Name:       "default",
+			Login:      c.Login,
+			Password:   c.Password,
+			Mode:       c.Mode,
+			CookieFile: c.CookieFile,
+			TOTPSecret: c.TOTPSecret,
+		}}
+	}
+	names := make(map[string]bool, len(accounts))
+	for i := range accounts {
+		acc := &accounts[i]
+		if !accountNameRe.MatchString(acc.Name) {
+			return nil, fmt.Errorf("wrong account name: '%s'", acc.Name)
+		}
+		if names[acc.Name] {
+			return nil, fmt.Errorf("duplicated account name: '%s'", acc.Name)
+		}
+		names[acc.Name] = true
+		if acc.Mode == "" {
+			acc.Mode = pages.REAL
+		}
+		if !pages.ValidMode(acc.Mode) {
+			return nil, fmt.Errorf("unknown mode of account %s: %s", acc.Name, acc.Mode)
+		}
+	}
+	return accounts, nil
+}
+
+// openAccount starts the browser and prepares the session
+func openAccount(cfg AccountConfig, db *repository.DB) (*account, error) {
+	// the intents left by a crash are older than any request of this run
+	lastItem, err := db.Items(cfg.Name).LastID()
+	if err != nil {
+		return nil, err
+	}
+	driver, network, err := openDriver()
+	if err != nil {
+		return nil, err
+	}
+	driver.SetPageLoadTimeout(config.Browser.pageLoadTimeout())
+	page := pages.Page{Driver: driver}
+
+	// every browser operation goes through a single executor
+	executor := pages.NewExecutor(driver)
+
+	// the server starts before the login and reports its state on /status
+	session := &pages.Session{
+		Name:       cfg.Name,
+		Page:       page,
+		URL:        config.TradingURL,
+		AccountURL: config.AccountURL,
+		Login:      cfg.Login,
+		Password:   cfg.Password,
+		Mode:       cfg.Mode,
+		TOTPSecret: cfg.TOTPSecret,
+	}
+	if cfg.CookieFile != "" {
+		if config.CookieSecret == "" {
+			log.Warnf("Cookie file of %s is not used, %s is not set", cfg.Name, cookieSecretEnv)
+		} else {
+			session.Cookies = &pages.CookieJar{Path: cfg.CookieFile, Secret: config.CookieSecret}
+		}
+	}
+
+	accountPage := &pages.AccountPage{Page: page, Network: network}
+	handlers := &api.Handler{
+		Account: cfg.Name,
+		Items:   db.Items(cfg.Name),
+		Orders:  db.Orders(cfg.Name),
+		Broker:  pages.NewSerialBroker(accountPage, executor, session),
+		Session: session,
+	}
+	if config.SnapshotInterval > 0 {
+		// a snapshot missing two refreshes in a row is stale
+		maxAge := time.Duration(config.SnapshotInterval) * time.Second * 2
+		handlers.Positions = api.NewPositionCache(handlers.Broker, maxAge)
+	}
+	return &account{
+		lastItem:   lastItem,
+		driver:     driver,
+		executor:   executor,
+		session:    session,
+		handlers:   handlers,
+		reconciler: api.NewReconciler(handlers),
+		stop:       make(chan struct{}),
+	}, nil
+}
+
+func (a *account) close() {
+	close(a.stop)
+	a.executor.Stop()
+	a.driver.Quit()
+}
+
+// runJobs waits for the login, settles the positions left by a crash,
+// refreshes the positions snapshot and reconciles the items with the platform
+func (a *account) runJobs() {
+	for !a.session.Status().LoggedIn {
+		select {
+		case <-a.stop:
+			return
+		case <-time.After(time.Second * 5):
+		}
+	}
+	if err := a.handlers.ResolveIntents(a.lastItem); err != nil {
+		log.WithField("account", a.handlers.Account).Error("Intents are not resolved: ", err)
+	}
+	if a.handlers.Positions != nil {
+		go a.handlers.Positions.Run(time.Duration(config.SnapshotInterval)*time.Second, a.stop)
+	}
+	if config.ReconcileInterval > 0 {
+		a.reconciler.Run(time.Duration(config.ReconcileInterval)*time.Second, a.stop)
+	}
+}
+
+// route adds the routes of the account under /accounts/{name}
+func (a *account) route(router *mux.Router) {
+	handlers := a.handlers
+	sub := router.PathPrefix(fmt.Sprintf("/accounts/%s", handlers.Account)).Subrouter()
+	sub.HandleFunc("/orders", handlers.AddOrder).Methods("POST")
+	sub.HandleFunc("/orders", handlers.GetOrders).Methods("GET")
+	sub.HandleFunc("/orders/{id:[0-9]+}", handlers.GetOrder).Methods("GET")
+	sub.HandleFunc("/orders/{id:[0-9]+}", handlers.DeleteOrder).Methods("DELETE")
+
+	sub.HandleFunc("/positions", handlers.Add).Methods("POST")
+	sub.HandleFunc("/positions", handlers.GetPositions).Methods("GET")
+	sub.HandleFunc("/positions/history", handlers.GetHistory).Methods("GET")
+	sub.HandleFunc("/positions/{id:[0-9]+}", handlers.GetPosition).Methods("GET")
+	sub.HandleFunc("/positions/{id:[0-9]+}", handlers.DeletePosition).Methods("DELETE")
+	sub.HandleFunc("/positions/{id:[0-9]+}", handlers.EditPosition).Methods("PUT")
+	sub.HandleFunc("/positions/{id:[0-9]+}/close", handlers.ClosePosition).Methods("POST")
+	sub.HandleFunc("/positions/{id:[0-9]+}/limits", handlers.EditLimits).Methods("PUT")
+
+	sub.HandleFunc("/reconciliation", a.reconciler.GetReport).Methods("GET")
+	sub.HandleFunc("/reconciliation/adopt/{guid}", a.reconciler.Adopt).Methods("POST")
+
+	sub.HandleFunc("/status", handlers.GetStatus).Methods("GET")
+	sub.HandleFunc("/admin/mode", handlers.SetMode).Methods("PUT")
+	sub.Use(handlers.WithMode)
+}
+
 func main() {
 	file, err := os.OpenFile("./logs.log", os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
@@ -57,68 +249,63 @@ func main() {
 		log.Fatalln("cant parse config:", err)
 		return
 	}
+	if secret := os.Getenv(cookieSecretEnv); secret != "" {
+		config.CookieSecret = secret
+	}
+	accountConfigs, err := config.accounts()
+	if err != nil {
+		log.Fatalln("cant parse config:", err)
+		return
+	}
 
 	// main database settings
-	dsn := config.Dsn
-	dsn += "&charset=utf8"
-	dsn += "&interpolateParams=true"
-	db, err := sql.Open("mysql", dsn)
-	db.SetMaxOpenConns(10)
-
-	// first connection
-	err = db.Ping()
+	db, err := repository.Open(config.Driver, config.Dsn)
 	if err != nil {
 		log.Fatalln(err)
 		return
 	}
 
-	// set browser as chrome
-	caps := selenium.Capabilities(map[string]interface{}{
-		"browserName": "chrome",
-	})
-
-	chromeCaps := chrome.Capabilities{
-		Path: "",
-		Args: []string{
-			"--headless",
-			"--no-sandbox",
-		},
-	}
-	caps.AddChrome(chromeCaps)
-
-	// connect to selenium server
-	driver, err = selenium.NewRemote(caps, config.HubURL)
+	// first connection
+	err = db.Ping()
 	if err != nil {
-		log.Fatalln("Failed to open session: ", err)
+		log.Fatalln(err)
 		return
 	}
-	defer driver.Quit()
-
-	driver.SetPageLoadTimeout(time.Second * 10)
-	page = pages.Page{Driver: driver}
-	err = page.Driver.Get(config.TradingURL)
 
-	if err != nil {
-		log.Fatalf("Failed to load page: %s\n", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			log.Fatalln("migration failed:", err)
+		}
 		return
 	}
-	login := pages.HomePage{Page: page}
-	accoutPage := login.LoginToAccount(config.Login, config.Password)
-	handlers := &api.Handler{
-		DB:          db,
-		AccountPage: accoutPage,
+	if config.AutoMigrate {
+		count, err := migrations.Up(db)
+		if err != nil {
+			log.Fatalln("migration failed:", err)
+			return
+		}
+		log.Infof("Applied %d migrations", count)
 	}
-	router := mux.NewRouter()
-	/*router.HandleFunc("/orders", handlers.Add).Methods("POST")
-	router.HandleFunc("/orders", handlers.GetOrders).Methods("GET")
-	router.HandleFunc("/orders/{id:[0-9]+}", handlers.GetOrder).Methods("GET")
-	router.HandleFunc("/orders/{id:[0-9]+}", handlers.DeleteOrder).Methods("DELETE")*/
 
-	router.HandleFunc("/positions", handlers.Add).Methods("POST")
-	router.HandleFunc("/positions", handlers.GetPositions).Methods("GET")
-	router.HandleFunc("/positions/{id:[0-9]+}", handlers.GetPosition).Methods("GET")
-	router.HandleFunc("/positions/{id:[0-9]+}", handlers.DeletePosition).Methods("DELETE")
-	router.HandleFunc("/positions/{id:[0-9]+}", handlers.EditPosition).Methods("PUT")
+	router := mux.NewRouter()
+	accounts := make([]*account, 0, len(accountConfigs))
+	hook := &pages.ModeHook{}
+	for _, cfg := range accountConfigs {
+		acc, err := openAccount(cfg, db)
+		if err != nil {
+			log.Fatalf("Failed to open session of %s: %s\n", cfg.Name, err)
+			return
+		}
+		accounts = append(accounts, acc)
+		hook.Sessions = append(hook.Sessions, acc.session)
+		acc.route(router)
+	}
+	log.AddHook(hook)
+	for _, acc := range accounts {
+		acc.session.Start(acc.executor)
+		go acc.runJobs()
+	}
 
 	router.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
 
@@ -144,5 +331,8 @@ func main() {
 	defer cancel()
 	srv.Shutdown(ctx)
 	log.Println("shutting down")
+	for _, acc := range accounts {
+		acc.close()
+	}
 	os.Exit(0)
 }